@@ -0,0 +1,409 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/ansible/recap.go
+
+// Package ansible parses ansible-playbook output into per-host recap counts
+// and task failures, for both the default text callback and the JSON
+// callback (ANSIBLE_STDOUT_CALLBACK=json), and fills
+// model.AnsibleCommandResult from them.
+package ansible
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// ErrNoRecap is returned when the output contains no play recap, e.g.
+// because the run was killed or failed before any play started.
+var ErrNoRecap = errors.New("ansible: no play recap in output")
+
+// HostStats are one host's PLAY RECAP counts.
+type HostStats struct {
+	Host        string `json:"host"`
+	Ok          int    `json:"ok"`
+	Changed     int    `json:"changed"`
+	Unreachable int    `json:"unreachable"`
+	Failed      int    `json:"failed"`
+	Skipped     int    `json:"skipped"`
+	Rescued     int    `json:"rescued"`
+	Ignored     int    `json:"ignored"`
+}
+
+// TaskFailure is a task that failed or could not reach a host.
+type TaskFailure struct {
+	Play        string `json:"play,omitempty"`
+	Task        string `json:"task"`
+	Host        string `json:"host"`
+	Item        string `json:"item,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Unreachable bool   `json:"unreachable,omitempty"`
+	Ignored     bool   `json:"ignored,omitempty"` // ignore_errors was set; text output only
+}
+
+// Recap is the parsed result of a playbook run.
+type Recap struct {
+	Hosts    []HostStats   `json:"hosts"` // ordered by host name
+	Failures []TaskFailure `json:"failures,omitempty"`
+}
+
+// Changed reports whether any host had changes.
+func (r *Recap) Changed() bool {
+	return r.Totals().Changed > 0
+}
+
+// Failed reports whether any host failed or was unreachable.
+func (r *Recap) Failed() bool {
+	t := r.Totals()
+	return t.Failed > 0 || t.Unreachable > 0
+}
+
+// Totals sums the counts of every host; Host is left empty.
+func (r *Recap) Totals() HostStats {
+	var t HostStats
+	for _, h := range r.Hosts {
+		t.Ok += h.Ok
+		t.Changed += h.Changed
+		t.Unreachable += h.Unreachable
+		t.Failed += h.Failed
+		t.Skipped += h.Skipped
+		t.Rescued += h.Rescued
+		t.Ignored += h.Ignored
+	}
+	return t
+}
+
+// Host returns the stats of one host.
+func (r *Recap) Host(name string) (HostStats, bool) {
+	for _, h := range r.Hosts {
+		if h.Host == name {
+			return h, true
+		}
+	}
+	return HostStats{}, false
+}
+
+// Stats renders the recap in the shape stored in
+// AnsibleCommandResult.Stats: host name to a map of counts, plus
+// "failures" listing the failed tasks.
+func (r *Recap) Stats() map[string]interface{} {
+	out := make(map[string]interface{}, len(r.Hosts)+1)
+	for _, h := range r.Hosts {
+		out[h.Host] = map[string]interface{}{
+			"ok":          h.Ok,
+			"changed":     h.Changed,
+			"unreachable": h.Unreachable,
+			"failed":      h.Failed,
+			"skipped":     h.Skipped,
+			"rescued":     h.Rescued,
+			"ignored":     h.Ignored,
+		}
+	}
+	if len(r.Failures) > 0 {
+		failures := make([]interface{}, len(r.Failures))
+		for i, f := range r.Failures {
+			failures[i] = map[string]interface{}{
+				"play": f.Play, "task": f.Task, "host": f.Host, "item": f.Item,
+				"message": f.Message, "unreachable": f.Unreachable, "ignored": f.Ignored,
+			}
+		}
+		out["failures"] = failures
+	}
+	return out
+}
+
+// Apply parses res.Output and sets Changed and Stats. When the run itself
+// succeeded but hosts failed, ErrorMessage summarizes the failures.
+func Apply(res *model.AnsibleCommandResult) error {
+	r, err := Parse(res.Output)
+	if err != nil {
+		return err
+	}
+	res.Changed = r.Changed()
+	res.Stats = r.Stats()
+	if r.Failed() && res.ErrorMessage == "" {
+		t := r.Totals()
+		res.ErrorMessage = fmt.Sprintf("ansible: %d failed, %d unreachable", t.Failed, t.Unreachable)
+	}
+	return nil
+}
+
+// Parse reads ansible-playbook output in either the JSON or the default
+// text callback format.
+func Parse(output string) (*Recap, error) {
+	if i := jsonStart(output); i >= 0 {
+		if r, err := ParseJSON(output[i:]); err == nil {
+			return r, nil
+		}
+	}
+	return ParseText(output)
+}
+
+// jsonStart finds the JSON document, skipping warnings printed before it.
+func jsonStart(s string) int {
+	for i := 0; i < len(s); {
+		if s[i] == '{' {
+			return i
+		}
+		nl := strings.IndexByte(s[i:], '\n')
+		if nl < 0 {
+			break
+		}
+		i += nl + 1
+	}
+	return -1
+}
+
+var (
+	ansiEscape  = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	headerLine  = regexp.MustCompile(`^(PLAY|TASK|RUNNING HANDLER|PLAY RECAP)\s*(?:\[(.*)\])?\s*\**\s*$`)
+	recapLine   = regexp.MustCompile(`^(\S+)\s+:\s+((?:[a-z]+=\d+\s*)+)$`)
+	recapField  = regexp.MustCompile(`([a-z]+)=(\d+)`)
+	failureLine = regexp.MustCompile(`^(fatal|failed): \[([^\]]+)\](?::\s*(FAILED|UNREACHABLE)!)?(?:\s*\(item=(.*?)\))?\s*=>\s*(.*)$`)
+	statusLine  = regexp.MustCompile(`^(ok|changed|skipping|fatal|failed|included|ignored|rescued): `)
+)
+
+// maxBodyLines caps how many lines a multi-line task result may span, so
+// a body that never becomes valid JSON cannot swallow the rest of the run.
+const maxBodyLines = 1000
+
+// endsBody reports whether line cannot belong to a multi-line task result.
+func endsBody(line string) bool {
+	return strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "...ignoring" ||
+		headerLine.MatchString(line) || statusLine.MatchString(line)
+}
+
+// ParseText parses the default callback's output.
+func ParseText(output string) (*Recap, error) {
+	r := &Recap{}
+	var play, task string
+	inRecap := false
+
+	sc := bufio.NewScanner(strings.NewReader(ansiEscape.ReplaceAllString(output, "")))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var lines []string
+	for sc.Scan() {
+		lines = append(lines, strings.TrimRight(sc.Text(), "\r"))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ansible: read output: %w", err)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := headerLine.FindStringSubmatch(line); m != nil {
+			switch m[1] {
+			case "PLAY":
+				play, inRecap = m[2], false
+			case "TASK", "RUNNING HANDLER":
+				task = m[2]
+			case "PLAY RECAP":
+				inRecap = true
+			}
+			continue
+		}
+		if inRecap {
+			if m := recapLine.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				r.Hosts = append(r.Hosts, parseRecapCounts(m[1], m[2]))
+			}
+			continue
+		}
+		if m := failureLine.FindStringSubmatch(line); m != nil {
+			f := TaskFailure{Play: play, Task: task, Host: m[2], Item: m[4], Unreachable: m[3] == "UNREACHABLE"}
+			body := m[5]
+			// Verbose runs pretty-print the result over several lines.
+			for n := 0; n < maxBodyLines && strings.HasPrefix(strings.TrimSpace(body), "{") && !json.Valid([]byte(body)) &&
+				i+1 < len(lines) && !endsBody(lines[i+1]); n++ {
+				i++
+				body += "\n" + lines[i]
+			}
+			f.Message = resultMessage(body)
+			if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) == "...ignoring" {
+				f.Ignored = true
+				i++
+			}
+			r.Failures = append(r.Failures, f)
+		}
+	}
+	if len(r.Hosts) == 0 {
+		return nil, ErrNoRecap
+	}
+	sortHosts(r.Hosts)
+	return r, nil
+}
+
+func parseRecapCounts(host, fields string) HostStats {
+	h := HostStats{Host: host}
+	for _, m := range recapField.FindAllStringSubmatch(fields, -1) {
+		n, _ := strconv.Atoi(m[2])
+		h = withCount(h, m[1], n)
+	}
+	return h
+}
+
+// resultMessage picks a human readable message out of a task result.
+func resultMessage(body string) string {
+	var res map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return strings.TrimSpace(body)
+	}
+	return messageOf(res)
+}
+
+func messageOf(res map[string]interface{}) string {
+	for _, k := range []string{"msg", "reason", "stderr", "module_stderr", "stdout"} {
+		if s, ok := res[k].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// jsonOutput is the JSON callback's document.
+type jsonOutput struct {
+	Plays []struct {
+		Play struct {
+			Name string `json:"name"`
+		} `json:"play"`
+		Tasks []struct {
+			Task struct {
+				Name string `json:"name"`
+			} `json:"task"`
+			Hosts map[string]map[string]interface{} `json:"hosts"`
+		} `json:"tasks"`
+	} `json:"plays"`
+	Stats map[string]map[string]int `json:"stats"`
+}
+
+// ParseJSON parses the JSON callback's output.
+func ParseJSON(output string) (*Recap, error) {
+	var doc jsonOutput
+	if err := json.NewDecoder(strings.NewReader(output)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("ansible: decode json output: %w", err)
+	}
+	if len(doc.Stats) == 0 {
+		return nil, ErrNoRecap
+	}
+	r := &Recap{}
+	for host, counts := range doc.Stats {
+		h := HostStats{Host: host}
+		for k, n := range counts {
+			h = withCount(h, k, n)
+		}
+		r.Hosts = append(r.Hosts, h)
+	}
+	sortHosts(r.Hosts)
+
+	for _, p := range doc.Plays {
+		for _, t := range p.Tasks {
+			hosts := make([]string, 0, len(t.Hosts))
+			for h := range t.Hosts {
+				hosts = append(hosts, h)
+			}
+			sort.Strings(hosts)
+			for _, h := range hosts {
+				res := t.Hosts[h]
+				unreachable, _ := res["unreachable"].(bool)
+				failed, _ := res["failed"].(bool)
+				if !failed && !unreachable {
+					continue
+				}
+				// The JSON callback does not record ignore_errors, so Ignored
+				// is only known from text output.
+				f := TaskFailure{Play: p.Play.Name, Task: t.Task.Name, Host: h, Unreachable: unreachable}
+				items := failedItems(res)
+				if len(items) == 0 {
+					f.Message = messageOf(res)
+					r.Failures = append(r.Failures, f)
+				}
+				for _, it := range items {
+					f.Item, f.Message = itemName(it), messageOf(it)
+					r.Failures = append(r.Failures, f)
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+// failedItems returns the failed item results of a loop task, which the
+// JSON callback nests under "results", as the text callback reports each
+// failed item on its own line.
+func failedItems(res map[string]interface{}) []map[string]interface{} {
+	results, _ := res["results"].([]interface{})
+	var out []map[string]interface{}
+	for _, r := range results {
+		item, ok := r.(map[string]interface{})
+		if failed, _ := item["failed"].(bool); ok && failed {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// itemName renders a loop item, which sits under the task's loop_var.
+func itemName(res map[string]interface{}) string {
+	key, _ := res["ansible_loop_var"].(string)
+	if key == "" {
+		key = "item"
+	}
+	switch v := res[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+func withCount(h HostStats, key string, n int) HostStats {
+	switch key {
+	case "ok":
+		h.Ok = n
+	case "changed":
+		h.Changed = n
+	case "unreachable":
+		h.Unreachable = n
+	case "failures", "failed":
+		h.Failed = n
+	case "skipped":
+		h.Skipped = n
+	case "rescued":
+		h.Rescued = n
+	case "ignored":
+		h.Ignored = n
+	}
+	return h
+}
+
+func sortHosts(hosts []HostStats) {
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+}
@@ -0,0 +1,177 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/ansible/recap_test.go
+
+package ansible
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// multiPlayHosts is the recap of default.txt and json.json, the same run
+// through the default and the JSON callback.
+var multiPlayHosts = []HostStats{
+	{Host: "db1", Unreachable: 1},
+	{Host: "db2", Ok: 2},
+	{Host: "web1", Ok: 5, Changed: 4, Skipped: 1, Ignored: 1},
+	{Host: "web2", Ok: 1, Failed: 1},
+}
+
+func TestParseText(t *testing.T) {
+	r, err := ParseText(readFixture(t, "default.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Hosts, multiPlayHosts) {
+		t.Errorf("hosts = %+v, want %+v", r.Hosts, multiPlayHosts)
+	}
+	want := []TaskFailure{
+		{Play: "Configure web servers", Task: "Install packages", Host: "web2", Item: "git",
+			Message: "Failed to install some of the specified packages"},
+		{Play: "Configure web servers", Task: "Check for legacy config", Host: "web1",
+			Message: "non-zero return code", Ignored: true},
+		{Play: "Configure databases", Task: "Gathering Facts", Host: "db1", Unreachable: true,
+			Message: "Failed to connect to the host via ssh: ssh: connect to host 10.0.0.31 port 22: Connection timed out"},
+	}
+	if !reflect.DeepEqual(r.Failures, want) {
+		t.Errorf("failures = %+v, want %+v", r.Failures, want)
+	}
+	if !r.Changed() || !r.Failed() {
+		t.Errorf("Changed() = %v, Failed() = %v, want both true", r.Changed(), r.Failed())
+	}
+}
+
+func TestParseTextPrettyColored(t *testing.T) {
+	r, err := ParseText(readFixture(t, "pretty.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHosts := []HostStats{{Host: "app1", Ok: 2, Rescued: 1}}
+	if !reflect.DeepEqual(r.Hosts, wantHosts) {
+		t.Errorf("hosts = %+v, want %+v", r.Hosts, wantHosts)
+	}
+	if len(r.Failures) != 1 || r.Failures[0].Message != "Status code was -1 and not [200]: Request failed: <urlopen error timed out>" {
+		t.Errorf("failures = %+v", r.Failures)
+	}
+	if r.Failed() {
+		t.Error("a rescued task should not fail the run")
+	}
+}
+
+func TestParseTextInvalidBody(t *testing.T) {
+	out := "TASK [x] ***\n" +
+		`fatal: [web1]: FAILED! => {"msg": "bad\u"}` + "\n" +
+		"\n" +
+		"PLAY RECAP ***\n" +
+		"web1 : ok=1 changed=0 unreachable=0 failed=1 skipped=0 rescued=0 ignored=0\n"
+	r, err := ParseText(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Hosts) != 1 || r.Hosts[0].Failed != 1 {
+		t.Errorf("hosts = %+v", r.Hosts)
+	}
+	if len(r.Failures) != 1 || r.Failures[0].Message != `{"msg": "bad\u"}` {
+		t.Errorf("failures = %+v", r.Failures)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	r, err := ParseJSON(readFixture(t, "json.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Hosts, multiPlayHosts) {
+		t.Errorf("hosts = %+v, want %+v", r.Hosts, multiPlayHosts)
+	}
+	// The JSON callback does not record ignore_errors, so the ignored
+	// failure is reported like any other.
+	want := []TaskFailure{
+		{Play: "Configure web servers", Task: "Install packages", Host: "web2", Item: "git",
+			Message: "Failed to install some of the specified packages"},
+		{Play: "Configure web servers", Task: "Check for legacy config", Host: "web1",
+			Message: "non-zero return code"},
+		{Play: "Configure databases", Task: "Gathering Facts", Host: "db1", Unreachable: true,
+			Message: "Failed to connect to the host via ssh: ssh: connect to host 10.0.0.31 port 22: Connection timed out"},
+	}
+	if !reflect.DeepEqual(r.Failures, want) {
+		t.Errorf("failures = %+v, want %+v", r.Failures, want)
+	}
+}
+
+func TestParseDetectsFormat(t *testing.T) {
+	for _, name := range []string{"default.txt", "json.json"} {
+		out := readFixture(t, name)
+		if name == "json.json" {
+			out = "[WARNING]: No inventory was parsed, only implicit localhost is available\n" + out
+		}
+		r, err := Parse(out)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(r.Hosts, multiPlayHosts) {
+			t.Errorf("%s: hosts = %+v", name, r.Hosts)
+		}
+	}
+}
+
+func TestParseNoRecap(t *testing.T) {
+	out := "ERROR! the playbook: site.yml could not be found\n"
+	if _, err := Parse(out); !errors.Is(err, ErrNoRecap) {
+		t.Errorf("Parse error = %v, want ErrNoRecap", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	res := &model.AnsibleCommandResult{Output: readFixture(t, "default.txt"), ExitCode: 2}
+	if err := Apply(res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.Changed {
+		t.Error("Changed = false, want true")
+	}
+	if res.ErrorMessage != "ansible: 1 failed, 1 unreachable" {
+		t.Errorf("ErrorMessage = %q", res.ErrorMessage)
+	}
+	web1, ok := res.Stats["web1"].(map[string]interface{})
+	if !ok || web1["changed"] != 4 || web1["ignored"] != 1 {
+		t.Errorf("Stats[web1] = %v", res.Stats["web1"])
+	}
+	if failures, _ := res.Stats["failures"].([]interface{}); len(failures) != 3 {
+		t.Errorf("Stats[failures] has %d entries, want 3", len(failures))
+	}
+}
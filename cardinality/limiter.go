@@ -0,0 +1,368 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/cardinality/limiter.go
+
+// Package cardinality guards metric storage against label explosions.
+// A Limiter tracks the distinct values seen for every attribute key of every
+// metric, identified by namespace, subnamespace and name, and the number of
+// distinct series per metric. Keys that exceed their limit are either
+// stripped (so points aggregate back together) or the points carrying them
+// are dropped. Offenders are reported as model.EventEntry
+// warnings. The Limiter is safe for concurrent use and can run in both the
+// agent and the server.
+package cardinality
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/histogram"
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+// Actions applied to data points that carry an offending label key.
+const (
+	// ActionAggregate removes the offending key and merges points whose
+	// remaining attributes are identical.
+	ActionAggregate = "aggregate"
+
+	// ActionDrop discards points that carry the offending key.
+	ActionDrop = "drop"
+)
+
+// Config controls the limits enforced by a Limiter.
+type Config struct {
+	// MaxValuesPerLabel is the number of distinct values a single attribute key
+	// may take for one metric. Zero disables the per-label check.
+	MaxValuesPerLabel int `yaml:"max_values_per_label" json:"max_values_per_label"`
+
+	// MaxSeriesPerMetric caps the distinct attribute sets per metric.
+	// Points that would create a new series beyond the cap are dropped.
+	// Zero disables the series check.
+	MaxSeriesPerMetric int `yaml:"max_series_per_metric" json:"max_series_per_metric"`
+
+	// Action is ActionAggregate (default) or ActionDrop.
+	Action string `yaml:"action" json:"action"`
+
+	// ExemptLabels are never counted or stripped (e.g. "endpoint_id").
+	ExemptLabels []string `yaml:"exempt_labels,omitempty" json:"exempt_labels,omitempty"`
+
+	// Window resets all tracked state after this long. Zero keeps state until
+	// Reset is called.
+	Window time.Duration `yaml:"window,omitempty" json:"window,omitempty"`
+}
+
+// Limiter tracks per-metric cardinality and enforces Config.
+type Limiter struct {
+	cfg    Config
+	exempt map[string]struct{}
+
+	mu      sync.Mutex
+	metrics map[string]*metricState // by metricKey
+	events  []model.EventEntry
+	started time.Time
+}
+
+// metricState holds what has been seen for one metric.
+type metricState struct {
+	values    map[string]map[string]struct{} // label key -> distinct values (capped)
+	offending map[string]bool                // label keys over the limit
+	series    map[string]struct{}            // distinct attribute sets (capped)
+	capped    bool                           // series cap already reported
+}
+
+// NewLimiter returns a Limiter for cfg.
+func NewLimiter(cfg Config) *Limiter {
+	if cfg.Action == "" {
+		cfg.Action = ActionAggregate
+	}
+	exempt := make(map[string]struct{}, len(cfg.ExemptLabels))
+	for _, k := range cfg.ExemptLabels {
+		exempt[k] = struct{}{}
+	}
+	return &Limiter{
+		cfg:     cfg,
+		exempt:  exempt,
+		metrics: make(map[string]*metricState),
+		started: time.Now(),
+	}
+}
+
+// Reset clears all tracked state. Pending events are kept.
+func (l *Limiter) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metrics = make(map[string]*metricState)
+	l.started = time.Now()
+}
+
+// Offenders returns the offending label keys per metric, keyed by
+// "namespace/subnamespace/name".
+func (l *Limiter) Offenders() map[string][]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make(map[string][]string)
+	for name, st := range l.metrics {
+		for k := range st.offending {
+			out[name] = append(out[name], k)
+		}
+		sort.Strings(out[name])
+	}
+	return out
+}
+
+// DrainEvents returns and clears the warning events raised since the last call.
+func (l *Limiter) DrainEvents() []model.EventEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ev := l.events
+	l.events = nil
+	return ev
+}
+
+// ApplyPayload runs Apply over every metric in the payload and removes metrics
+// left without data points. It returns the number of dropped data points.
+func (l *Limiter) ApplyPayload(p *model.MetricPayload) int {
+	if p == nil {
+		return 0
+	}
+	dropped := 0
+	kept := p.Metrics[:0]
+	for i := range p.Metrics {
+		m := &p.Metrics[i]
+		if m.Meta == nil {
+			m.Meta = p.Meta
+			dropped += l.Apply(m)
+			m.Meta = nil
+		} else {
+			dropped += l.Apply(m)
+		}
+		if len(m.DataPoints) > 0 {
+			kept = append(kept, *m)
+		}
+	}
+	p.Metrics = kept
+	return dropped
+}
+
+// Apply enforces the limits on a single metric in place and returns the number
+// of data points that were dropped or merged away.
+func (l *Limiter) Apply(m *model.Metric) int {
+	if m == nil || len(m.DataPoints) == 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.Window > 0 && time.Since(l.started) > l.cfg.Window {
+		l.metrics = make(map[string]*metricState)
+		l.started = time.Now()
+	}
+
+	key := metricKey(m)
+	st := l.metrics[key]
+	if st == nil {
+		st = &metricState{
+			values:    make(map[string]map[string]struct{}),
+			offending: make(map[string]bool),
+			series:    make(map[string]struct{}),
+		}
+		l.metrics[key] = st
+	}
+
+	// First pass: learn label values and flag offending keys.
+	if l.cfg.MaxValuesPerLabel > 0 {
+		for _, dp := range m.DataPoints {
+			for k, v := range dp.Attributes {
+				if _, ok := l.exempt[k]; ok || st.offending[k] {
+					continue
+				}
+				vals := st.values[k]
+				if vals == nil {
+					vals = make(map[string]struct{})
+					st.values[k] = vals
+				}
+				vals[v] = struct{}{}
+				if len(vals) > l.cfg.MaxValuesPerLabel {
+					st.offending[k] = true
+					delete(st.values, k)
+					l.report(m, fmt.Sprintf("metric %q label %q exceeded %d distinct values; action=%s",
+						m.Name, k, l.cfg.MaxValuesPerLabel, l.cfg.Action), k)
+				}
+			}
+		}
+	}
+
+	before := len(m.DataPoints)
+	points := m.DataPoints[:0]
+	for _, dp := range m.DataPoints {
+		if len(st.offending) > 0 {
+			hit := false
+			for k := range dp.Attributes {
+				if st.offending[k] {
+					hit = true
+					break
+				}
+			}
+			if hit {
+				if l.cfg.Action == ActionDrop {
+					continue
+				}
+				dp.Attributes = stripKeys(dp.Attributes, st.offending)
+			}
+		}
+
+		if l.cfg.MaxSeriesPerMetric > 0 {
+			key := seriesKey(dp.Attributes)
+			if _, ok := st.series[key]; !ok {
+				if len(st.series) >= l.cfg.MaxSeriesPerMetric {
+					if !st.capped {
+						st.capped = true
+						l.report(m, fmt.Sprintf("metric %q exceeded %d series; new series are dropped",
+							m.Name, l.cfg.MaxSeriesPerMetric), "")
+					}
+					continue
+				}
+				st.series[key] = struct{}{}
+			}
+		}
+		points = append(points, dp)
+	}
+	m.DataPoints = points
+
+	if l.cfg.Action == ActionAggregate && len(st.offending) > 0 {
+		m.DataPoints = aggregate(m.DataType, m.DataPoints)
+	}
+	return before - len(m.DataPoints)
+}
+
+// metricKey identifies a metric across namespaces, so that e.g. System and
+// Container "cpu.usage" get separate budgets.
+func metricKey(m *model.Metric) string {
+	return m.Namespace + "/" + m.SubNamespace + "/" + m.Name
+}
+
+// report queues a warning event. Caller must hold l.mu.
+func (l *Limiter) report(m *model.Metric, msg, label string) {
+	meta := map[string]string{
+		"metric":       m.Name,
+		"namespace":    m.Namespace,
+		"subnamespace": m.SubNamespace,
+		"action":       l.cfg.Action,
+	}
+	if label != "" {
+		meta["label"] = label
+	}
+
+	endpointID := ""
+	if m.Meta != nil {
+		endpointID = m.Meta.EndpointID
+	}
+	target := endpointID
+	if target == "" {
+		target = m.Name
+	}
+
+	l.events = append(l.events, model.EventEntry{
+		ID:         utils.NewUUID(),
+		Timestamp:  time.Now(),
+		Level:      "warning",
+		Type:       "system",
+		Category:   "metric",
+		Message:    msg,
+		Source:     m.Name,
+		Scope:      "endpoint",
+		Target:     target,
+		EndpointID: endpointID,
+		Meta:       meta,
+	})
+	utils.Warn("cardinality: %s", msg)
+}
+
+// stripKeys returns a copy of attrs without the offending keys.
+func stripKeys(attrs map[string]string, offending map[string]bool) map[string]string {
+	out := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		if !offending[k] {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// seriesKey builds a stable identity for an attribute set.
+func seriesKey(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(attrs[k])
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+// aggregate merges points with identical attributes. Sums and histograms are
+// added together; for gauges and anything else the latest point wins.
+func aggregate(dataType string, points []model.DataPoint) []model.DataPoint {
+	index := make(map[string]int, len(points))
+	out := points[:0]
+	for _, dp := range points {
+		key := seriesKey(dp.Attributes)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, dp)
+			continue
+		}
+
+		cur := &out[i]
+		switch strings.ToLower(dataType) {
+		case "sum", "counter":
+			cur.Value += dp.Value
+			if dp.Timestamp.After(cur.Timestamp) {
+				cur.Timestamp = dp.Timestamp
+			}
+		case "histogram":
+			if merged, err := histogram.Merge(cur, &dp); err == nil {
+				*cur = *merged
+			}
+		default:
+			if !dp.Timestamp.Before(cur.Timestamp) {
+				*cur = dp
+			}
+		}
+	}
+	return out
+}
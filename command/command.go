@@ -0,0 +1,151 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/command.go
+
+// Package command holds the plumbing shared by the server and agents for
+// remote command execution: conversion between the wire messages in the
+// proto package and the model types, and chunked streaming of command
+// output.
+package command
+
+import (
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/proto"
+)
+
+// RequestToProto converts a model request to its wire form. Timeouts are
+// rounded up to whole seconds.
+func RequestToProto(r *model.CommandRequest) *proto.CommandRequest {
+	timeout := int32((r.Timeout + time.Second - 1) / time.Second)
+	return &proto.CommandRequest{
+		AgentId:           r.AgentID,
+		CommandType:       r.CommandType,
+		Command:           r.CommandData,
+		Args:              r.Args,
+		RequestId:         r.RequestID,
+		TimeoutSeconds:    timeout,
+		Env:               r.Env,
+		WorkingDir:        r.WorkingDir,
+		RunAsUser:         r.RunAsUser,
+		Stdin:             r.Stdin,
+		StreamOutput:      r.StreamOutput,
+		RequestedBy:       r.RequestedBy,
+		Nonce:             r.Nonce,
+		IssuedAtUnixNano:  unixNano(r.IssuedAt),
+		ExpiresAtUnixNano: unixNano(r.ExpiresAt),
+		KeyId:             r.KeyID,
+		Signature:         r.Signature,
+	}
+}
+
+// RequestFromProto converts a wire request to the model type.
+func RequestFromProto(p *proto.CommandRequest) *model.CommandRequest {
+	return &model.CommandRequest{
+		AgentID:      p.GetAgentId(),
+		CommandType:  p.GetCommandType(),
+		CommandData:  p.GetCommand(),
+		Args:         p.GetArgs(),
+		RequestID:    p.GetRequestId(),
+		Timeout:      time.Duration(p.GetTimeoutSeconds()) * time.Second,
+		Env:          p.GetEnv(),
+		WorkingDir:   p.GetWorkingDir(),
+		RunAsUser:    p.GetRunAsUser(),
+		Stdin:        p.GetStdin(),
+		StreamOutput: p.GetStreamOutput(),
+		RequestedBy:  p.GetRequestedBy(),
+		Nonce:        p.GetNonce(),
+		IssuedAt:     fromUnixNano(p.GetIssuedAtUnixNano()),
+		ExpiresAt:    fromUnixNano(p.GetExpiresAtUnixNano()),
+		KeyID:        p.GetKeyId(),
+		Signature:    p.GetSignature(),
+	}
+}
+
+// ResultToProto builds the final response for a finished shell or ansible
+// run. Success means a zero exit code and no execution error.
+func ResultToProto(requestID string, r *model.ShellCommandResult) *proto.CommandResponse {
+	resp := &proto.CommandResponse{
+		Success:      r.ExitCode == 0 && r.ErrorMessage == "" && !r.TimedOut,
+		Output:       r.Output,
+		ErrorMessage: r.ErrorMessage,
+		RequestId:    requestID,
+		ExitCode:     int32(r.ExitCode),
+		DurationMs:   r.Duration.Milliseconds(),
+		TimedOut:     r.TimedOut,
+	}
+	resp.StartedAtUnixNano = unixNano(r.StartedAt)
+	return resp
+}
+
+// ResponseFromProto converts a wire response to the model type.
+func ResponseFromProto(p *proto.CommandResponse) *model.CommandResponse {
+	resp := &model.CommandResponse{
+		Success:      p.GetSuccess(),
+		Output:       p.GetOutput(),
+		ErrorMessage: p.GetErrorMessage(),
+		RequestID:    p.GetRequestId(),
+		ExitCode:     int(p.GetExitCode()),
+		Duration:     time.Duration(p.GetDurationMs()) * time.Millisecond,
+		TimedOut:     p.GetTimedOut(),
+	}
+	resp.StartedAt = fromUnixNano(p.GetStartedAtUnixNano())
+	return resp
+}
+
+// ChunkToProto converts an output chunk to its wire form.
+func ChunkToProto(c *model.CommandOutputChunk) *proto.CommandOutputChunk {
+	return &proto.CommandOutputChunk{
+		RequestId:         c.RequestID,
+		Stream:            c.Stream,
+		Data:              c.Data,
+		Sequence:          c.Sequence,
+		TimestampUnixNano: c.Timestamp.UnixNano(),
+	}
+}
+
+// ChunkFromProto converts a wire output chunk to the model type.
+func ChunkFromProto(p *proto.CommandOutputChunk) *model.CommandOutputChunk {
+	return &model.CommandOutputChunk{
+		RequestID: p.GetRequestId(),
+		Stream:    p.GetStream(),
+		Data:      p.GetData(),
+		Sequence:  p.GetSequence(),
+		Timestamp: time.Unix(0, p.GetTimestampUnixNano()),
+	}
+}
+
+// unixNano maps the zero time to 0 rather than a large negative number.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
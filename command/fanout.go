@@ -0,0 +1,333 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/fanout.go
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+// Dispatch sends a command to one endpoint and waits for its result. It
+// sets the request's AgentID to the endpoint's agent, and signs it if
+// commands are signed, before sending.
+type Dispatch func(ctx context.Context, endpointID string, req *model.CommandRequest) (model.CommandResult, error)
+
+// RegistryDispatch returns a Dispatch that registers each command with reg,
+// hands it to send, which addresses, signs and delivers it to the agent, and
+// waits for the response.
+// A command still pending when ctx ends is canceled.
+func RegistryDispatch(reg *Registry, send func(endpointID string, req *model.CommandRequest) error) Dispatch {
+	return func(ctx context.Context, endpointID string, req *model.CommandRequest) (model.CommandResult, error) {
+		p, err := reg.Register(endpointID, req)
+		if err != nil {
+			return model.CommandResult{}, err
+		}
+		if err := send(endpointID, req); err != nil {
+			_ = reg.Cancel(p.RequestID, fmt.Sprintf("send failed: %v", err))
+			return model.CommandResult{}, fmt.Errorf("command: send to %s: %w", endpointID, err)
+		}
+		if res, err := p.Wait(ctx); ctx.Err() == nil {
+			return res, err
+		}
+		_ = reg.Cancel(p.RequestID, "fan-out canceled") // may have just resolved
+		return p.Result()
+	}
+}
+
+// Matches reports whether an endpoint ID and its labels satisfy sel. The
+// OnlineOnly condition is left to the callers that know the status.
+func Matches(sel model.TargetSelector, endpointID string, labels map[string]string) bool {
+	if len(sel.EndpointIDs) > 0 && !contains(sel.EndpointIDs, endpointID) {
+		return false
+	}
+	for k, v := range sel.Labels {
+		got, ok := labels[k]
+		if !ok || (v != "*" && got != v) {
+			return false
+		}
+	}
+	for _, r := range sel.Expressions {
+		got, ok := labels[r.Key]
+		switch r.Operator {
+		case model.LabelOpIn:
+			if !ok || !contains(r.Values, got) {
+				return false
+			}
+		case model.LabelOpNotIn:
+			if ok && contains(r.Values, got) {
+				return false
+			}
+		case model.LabelOpExists:
+			if !ok {
+				return false
+			}
+		case model.LabelOpDoesNotExist:
+			if ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Targets returns the sorted IDs of the endpoints matched by sel.
+func Targets(sel model.TargetSelector, endpoints []model.Endpoint) []string {
+	var ids []string
+	for _, e := range endpoints {
+		if sel.OnlineOnly && e.Status != "online" {
+			continue
+		}
+		if Matches(sel, e.EndpointID, e.Labels) {
+			ids = append(ids, e.EndpointID)
+		}
+	}
+	return dedupe(ids)
+}
+
+// AgentTargets returns the sorted endpoint IDs of the agents matched by sel.
+func AgentTargets(sel model.TargetSelector, agents []model.Agent) []string {
+	var ids []string
+	for _, a := range agents {
+		if sel.OnlineOnly && a.Status != "online" {
+			continue
+		}
+		if Matches(sel, a.EndpointID, a.Labels) {
+			ids = append(ids, a.EndpointID)
+		}
+	}
+	return dedupe(ids)
+}
+
+// FanOut runs req.Command on every target through dispatch. Canaries run
+// first and must all succeed; the remaining endpoints run at most
+// req.Concurrency at a time until they are done, the failure threshold is
+// crossed or ctx ends. Endpoints that were never started are reported as
+// skipped. Commands already running when the fan-out stops are allowed to
+// finish.
+func FanOut(ctx context.Context, req *model.FanOutRequest, targets []string, dispatch Dispatch) *model.FanOutResult {
+	if req.ID == "" {
+		req.ID = utils.NewUUID()
+	}
+	out := &model.FanOutResult{
+		ID:        req.ID,
+		StartedAt: time.Now(),
+		Results:   make([]model.CommandResult, len(targets)),
+	}
+	f := &fanOut{req: req, targets: targets, dispatch: dispatch, out: out}
+
+	canaries := canaryCount(req.Canary, len(targets))
+	f.run(ctx, 0, canaries)
+	if !f.stopped && canaries > 0 && f.failed > 0 {
+		f.stop(fmt.Sprintf("%d of %d canaries failed", f.failed, canaries))
+	}
+	if !f.stopped {
+		f.run(ctx, canaries, len(targets))
+	}
+
+	for i, id := range targets {
+		if out.Results[i].Status == "" {
+			out.Results[i] = model.CommandResult{
+				EndpointID:   id,
+				ErrorMessage: f.reason,
+				Timestamp:    time.Now().UTC().Format(time.RFC3339),
+				Status:       model.CommandStatusSkipped,
+				ExitCode:     -1,
+			}
+		}
+	}
+	out.Summary = Summarize(out.Results)
+	out.Summary.DurationMs = time.Since(out.StartedAt).Milliseconds()
+	out.Summary.Stopped, out.Summary.StopReason = f.stopped, f.reason
+	return out
+}
+
+// Summarize aggregates fan-out results.
+func Summarize(results []model.CommandResult) model.FanOutSummary {
+	s := model.FanOutSummary{Targeted: len(results)}
+	var ran, total int64
+	for _, r := range results {
+		switch r.Status {
+		case model.CommandStatusSkipped:
+			s.Skipped++
+			continue
+		case model.CommandStatusSuccess:
+			s.Succeeded++
+		case model.CommandStatusTimeout:
+			s.TimedOut++
+		case model.CommandStatusCanceled:
+			s.Canceled++
+		default:
+			s.Failed++
+		}
+		if s.ExitCodes == nil {
+			s.ExitCodes = map[int]int{}
+		}
+		s.ExitCodes[r.ExitCode]++
+		ran++
+		total += r.DurationMs
+		if r.DurationMs > s.MaxDurationMs {
+			s.MaxDurationMs = r.DurationMs
+		}
+	}
+	if ran > 0 {
+		s.AvgDurationMs = total / ran
+	}
+	if s.Targeted > 0 {
+		s.FailurePercent = float64(s.Failed+s.TimedOut+s.Canceled) * 100 / float64(s.Targeted)
+	}
+	return s
+}
+
+// fanOut is the state of one FanOut call.
+type fanOut struct {
+	req      *model.FanOutRequest
+	targets  []string
+	dispatch Dispatch
+	out      *model.FanOutResult
+
+	mu      sync.Mutex
+	failed  int
+	stopped bool
+	reason  string
+}
+
+// run dispatches targets[from:to], at most Concurrency at a time.
+func (f *fanOut) run(ctx context.Context, from, to int) {
+	limit := f.req.Concurrency
+	if limit <= 0 {
+		limit = 10
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := from; i < to; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			f.stop("canceled")
+		}
+		if f.isStopped() {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f.record(i, f.exec(ctx, f.targets[i]))
+		}(i)
+	}
+	wg.Wait()
+}
+
+// exec runs the command on one endpoint.
+func (f *fanOut) exec(ctx context.Context, endpointID string) model.CommandResult {
+	cmd := f.req.Command
+	cmd.RequestID = utils.NewUUID()
+	start := time.Now()
+	res, err := f.dispatch(ctx, endpointID, &cmd)
+	if res.Status == "" {
+		res.EndpointID = endpointID
+		res.RequestID = cmd.RequestID
+		res.Timestamp = time.Now().UTC().Format(time.RFC3339)
+		res.DurationMs = time.Since(start).Milliseconds()
+		res.Status = model.CommandStatusSuccess
+		if !res.Success {
+			res.Status = model.CommandStatusFailed
+		}
+		if err != nil {
+			res.Success = false
+			res.ExitCode = -1
+			res.ErrorMessage = err.Error()
+			res.Status = model.CommandStatusFailed
+			if errors.Is(err, context.Canceled) {
+				res.Status = model.CommandStatusCanceled
+			}
+		}
+	}
+	return res
+}
+
+// record stores a result and stops the fan-out once too many targets failed.
+func (f *fanOut) record(i int, res model.CommandResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.out.Results[i] = res
+	if res.Status == model.CommandStatusSuccess {
+		return
+	}
+	f.failed++
+	max := f.req.MaxFailurePercent
+	if pct := float64(f.failed) * 100 / float64(len(f.targets)); max > 0 && pct > max && !f.stopped {
+		f.stopped = true
+		f.reason = fmt.Sprintf("failure rate %.1f%% exceeded %.1f%%", pct, max)
+	}
+}
+
+func (f *fanOut) stop(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.stopped {
+		f.stopped, f.reason = true, reason
+	}
+}
+
+func (f *fanOut) isStopped() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stopped
+}
+
+// canaryCount returns the canary batch size for n targets.
+func canaryCount(c model.Canary, n int) int {
+	count := c.Count
+	if p := int(math.Ceil(c.Percent * float64(n) / 100)); p > count {
+		count = p
+	}
+	if count > n {
+		count = n
+	}
+	return count
+}
+
+func dedupe(ids []string) []string {
+	sort.Strings(ids)
+	out := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
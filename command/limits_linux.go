@@ -0,0 +1,48 @@
+//go:build linux
+
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/limits_linux.go
+
+package command
+
+import "strconv"
+
+const limitsSupported = true
+
+// wrapLimits runs argv under "sh -c 'ulimit ...; exec \"$@\"'", so the
+// limits are in place before the command's first instruction.
+func wrapLimits(l Limits, argv []string) []string {
+	script := ""
+	if l.CPUSeconds > 0 {
+		script += "ulimit -t " + strconv.Itoa(l.CPUSeconds) + " && "
+	}
+	if l.MemoryBytes > 0 {
+		kib := (l.MemoryBytes + 1023) / 1024
+		script += "ulimit -v " + strconv.FormatInt(kib, 10) + " && "
+	}
+	if l.OpenFiles > 0 {
+		script += "ulimit -n " + strconv.Itoa(l.OpenFiles) + " && "
+	}
+	script += `exec "$@"`
+	return append([]string{"/bin/sh", "-c", script, "sh"}, argv...)
+}
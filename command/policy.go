@@ -0,0 +1,223 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/policy.go
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+// ErrNotAllowed is returned for requests the policy rejects.
+var ErrNotAllowed = errors.New("command: not allowed by policy")
+
+// EventCategoryAudit is the category of the events a Policy emits.
+const EventCategoryAudit = "audit"
+
+// Policy authorizes incoming commands on the agent: it verifies the
+// signature, applies the allowlist and records an audit event for every
+// decision. It is safe for concurrent use.
+type Policy struct {
+	cfg        model.CommandPolicy
+	verifier   *Verifier
+	rules      []compiledRule
+	endpointID string
+
+	mu      sync.Mutex
+	events  []model.EventEntry
+	dropped int // audit events discarded since the last DrainEvents
+}
+
+type compiledRule struct {
+	model.CommandPolicyRule
+	commands []*regexp.Regexp
+}
+
+// NewPolicy compiles a policy. verifier may be nil only if the policy
+// allows unsigned requests. endpointID labels the audit events.
+func NewPolicy(cfg model.CommandPolicy, verifier *Verifier, endpointID string) (*Policy, error) {
+	if verifier == nil && !cfg.AllowUnsigned {
+		return nil, fmt.Errorf("command: policy requires a verifier unless allow_unsigned is set")
+	}
+	if cfg.MaxAuditEvents <= 0 {
+		cfg.MaxAuditEvents = 1000
+	}
+	p := &Policy{cfg: cfg, verifier: verifier, endpointID: endpointID}
+	for _, r := range cfg.Rules {
+		cr := compiledRule{CommandPolicyRule: r}
+		for _, pat := range r.Commands {
+			re, err := regexp.Compile("^(?:" + pat + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("command: policy rule %s: %w", r.Name, err)
+			}
+			cr.commands = append(cr.commands, re)
+		}
+		p.rules = append(p.rules, cr)
+	}
+	return p, nil
+}
+
+// Authorize returns nil if the request may run. Every call is audited.
+func (p *Policy) Authorize(r *model.CommandRequest) error {
+	err := p.authorize(r)
+	p.audit(r, err)
+	return err
+}
+
+func (p *Policy) authorize(r *model.CommandRequest) error {
+	if p.verifier != nil {
+		if err := p.verifier.Verify(r); err != nil {
+			if !(p.cfg.AllowUnsigned && errors.Is(err, ErrUnsigned)) {
+				return err
+			}
+		}
+	}
+	line := CommandLine(r)
+	for _, rule := range p.rules {
+		if !rule.matches(r, line) {
+			continue
+		}
+		if rule.Deny {
+			return fmt.Errorf("%w: denied by rule %s", ErrNotAllowed, rule.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: no rule matches", ErrNotAllowed)
+}
+
+// CommandLine is the text policy command patterns are matched against: the
+// command followed by its arguments, space separated.
+func CommandLine(r *model.CommandRequest) string {
+	if len(r.Args) == 0 {
+		return r.CommandData
+	}
+	return r.CommandData + " " + strings.Join(r.Args, " ")
+}
+
+func (rule *compiledRule) matches(r *model.CommandRequest, line string) bool {
+	if len(rule.Types) > 0 && !contains(rule.Types, r.CommandType) {
+		return false
+	}
+	if len(rule.Users) > 0 && !contains(rule.Users, r.RequestedBy) {
+		return false
+	}
+	if len(rule.RunAs) > 0 && !contains(rule.RunAs, r.RunAsUser) {
+		return false
+	}
+	if len(rule.commands) == 0 {
+		return true
+	}
+	for _, re := range rule.commands {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// audit records the decision for r, dropping the oldest buffered event when
+// the buffer is full.
+func (p *Policy) audit(r *model.CommandRequest, err error) {
+	level, outcome := "info", "allowed"
+	msg := fmt.Sprintf("%s command %s allowed", r.CommandType, r.RequestID)
+	if err != nil {
+		level, outcome = "warning", "rejected"
+		msg = fmt.Sprintf("%s command %s rejected: %v", r.CommandType, r.RequestID, err)
+		utils.Warn("command: %s", msg)
+	}
+	line := CommandLine(r)
+	if len(line) > 256 {
+		line = line[:256] + "..."
+	}
+	ev := p.event(level, msg, map[string]string{
+		"outcome":      outcome,
+		"request_id":   r.RequestID,
+		"command_type": r.CommandType,
+		"command":      line,
+		"requested_by": r.RequestedBy,
+		"run_as":       r.RunAsUser,
+		"key_id":       r.KeyID,
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.events) >= p.cfg.MaxAuditEvents {
+		n := len(p.events) - p.cfg.MaxAuditEvents + 1
+		p.events = append(p.events[:0], p.events[n:]...)
+		p.dropped += n
+	}
+	p.events = append(p.events, ev)
+}
+
+func (p *Policy) event(level, msg string, meta map[string]string) model.EventEntry {
+	return model.EventEntry{
+		ID:         utils.NewUUID(),
+		Timestamp:  time.Now(),
+		Level:      level,
+		Type:       "system",
+		Category:   EventCategoryAudit,
+		Message:    msg,
+		Source:     "command",
+		Scope:      "endpoint",
+		Target:     p.endpointID,
+		EndpointID: p.endpointID,
+		Meta:       meta,
+	}
+}
+
+// DrainEvents returns and clears the audit events recorded so far. If
+// events were dropped because the buffer was full, a warning event with the
+// count comes first.
+func (p *Policy) DrainEvents() []model.EventEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ev := p.events
+	if p.dropped > 0 {
+		msg := fmt.Sprintf("%d command audit events dropped", p.dropped)
+		utils.Warn("command: %s", msg)
+		ev = append([]model.EventEntry{p.event("warning", msg, map[string]string{
+			"outcome": "dropped",
+			"dropped": strconv.Itoa(p.dropped),
+		})}, ev...)
+		p.dropped = 0
+	}
+	p.events = nil
+	return ev
+}
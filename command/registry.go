@@ -0,0 +1,342 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/registry.go
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+var (
+	ErrUnknownRequest   = errors.New("command: unknown request id")
+	ErrDuplicateRequest = errors.New("command: request id already pending")
+	ErrTimeout          = errors.New("command: timed out waiting for result")
+	ErrCanceled         = errors.New("command: canceled")
+)
+
+// RegistryConfig controls a Registry.
+type RegistryConfig struct {
+	// DefaultTimeout applies to requests without a Timeout. Default 5m.
+	DefaultTimeout time.Duration
+
+	// Grace is added to a request's own Timeout before the server gives up,
+	// leaving the agent time to report its timeout. Default 10s.
+	Grace time.Duration
+
+	// HistorySize is how many finished results are kept. Default 1000.
+	HistorySize int
+
+	// MaxOutput caps the output collected from streamed chunks. Default 1 MiB.
+	MaxOutput int
+
+	// OnCancel is called, outside the registry lock, when a pending command
+	// is canceled or expires, so the server can send a CommandCancel to the
+	// agent.
+	OnCancel func(endpointID, requestID, reason string)
+}
+
+// Registry tracks commands sent to agents until their response arrives,
+// and keeps a bounded history of results keyed by request ID. It is safe
+// for concurrent use.
+type Registry struct {
+	cfg RegistryConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*Pending
+	history map[string]model.CommandResult
+	order   []string // history, oldest first
+}
+
+// Pending is a command awaiting its result.
+type Pending struct {
+	RequestID  string
+	EndpointID string
+	Request    model.CommandRequest // as registered; changes made afterwards, such as addressing and signing, are not reflected
+	Sent       time.Time
+	Deadline   time.Time
+
+	done   chan struct{}
+	result model.CommandResult
+	err    error
+	output strings.Builder
+}
+
+// NewRegistry creates a Registry.
+func NewRegistry(cfg RegistryConfig) *Registry {
+	if cfg.DefaultTimeout <= 0 {
+		cfg.DefaultTimeout = 5 * time.Minute
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = 10 * time.Second
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = 1000
+	}
+	if cfg.MaxOutput <= 0 {
+		cfg.MaxOutput = 1 << 20
+	}
+	return &Registry{
+		cfg:     cfg,
+		now:     time.Now,
+		pending: map[string]*Pending{},
+		history: map[string]model.CommandResult{},
+	}
+}
+
+// Register records a command about to be sent to an endpoint. A missing
+// RequestID is generated and written back to req. The Pending keeps a copy
+// of req, so register before setting AgentID and signing: the signature
+// covers the RequestID.
+func (r *Registry) Register(endpointID string, req *model.CommandRequest) (*Pending, error) {
+	if req.RequestID == "" {
+		req.RequestID = utils.NewUUID()
+	}
+	now := r.now()
+	timeout := r.cfg.DefaultTimeout
+	if req.Timeout > 0 {
+		timeout = req.Timeout + r.cfg.Grace
+	}
+	p := &Pending{
+		RequestID:  req.RequestID,
+		EndpointID: endpointID,
+		Request:    *req,
+		Sent:       now,
+		Deadline:   now.Add(timeout),
+		done:       make(chan struct{}),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[p.RequestID]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateRequest, p.RequestID)
+	}
+	r.pending[p.RequestID] = p
+	return p, nil
+}
+
+// Done is closed once the command has a result, failed or was canceled.
+func (p *Pending) Done() <-chan struct{} {
+	return p.done
+}
+
+// Result returns the outcome after Done is closed. The error is ErrTimeout
+// or ErrCanceled when no response arrived.
+func (p *Pending) Result() (model.CommandResult, error) {
+	<-p.done
+	return p.result, p.err
+}
+
+// Wait blocks until the command finishes or ctx ends.
+func (p *Pending) Wait(ctx context.Context) (model.CommandResult, error) {
+	select {
+	case <-p.done:
+		return p.result, p.err
+	case <-ctx.Done():
+		return model.CommandResult{}, ctx.Err()
+	}
+}
+
+// AddOutput appends a streamed chunk to the pending command's output.
+func (r *Registry) AddOutput(c *model.CommandOutputChunk) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pending[c.RequestID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRequest, c.RequestID)
+	}
+	if room := r.cfg.MaxOutput - p.output.Len(); room > 0 {
+		data := c.Data
+		if len(data) > room {
+			data = data[:room]
+		}
+		p.output.Write(data)
+	}
+	return nil
+}
+
+// Resolve completes a pending command with the agent's response.
+func (r *Registry) Resolve(resp *model.CommandResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pending[resp.RequestID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRequest, resp.RequestID)
+	}
+	output := resp.Output
+	if output == "" {
+		output = p.output.String() // streamed runs may only send chunks
+	}
+	status := model.CommandStatusSuccess
+	switch {
+	case resp.TimedOut:
+		status = model.CommandStatusTimeout
+	case !resp.Success:
+		status = model.CommandStatusFailed
+	}
+	r.finish(p, model.CommandResult{
+		EndpointID:   p.EndpointID,
+		Output:       output,
+		Success:      resp.Success,
+		ErrorMessage: resp.ErrorMessage,
+		Timestamp:    r.now().UTC().Format(time.RFC3339),
+		RequestID:    p.RequestID,
+		Status:       status,
+		ExitCode:     resp.ExitCode,
+		DurationMs:   resp.Duration.Milliseconds(),
+	}, nil)
+	return nil
+}
+
+// Cancel abandons a pending command and notifies OnCancel.
+func (r *Registry) Cancel(requestID, reason string) error {
+	r.mu.Lock()
+	p, ok := r.pending[requestID]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownRequest, requestID)
+	}
+	r.finish(p, r.abandoned(p, model.CommandStatusCanceled, reason), ErrCanceled)
+	r.mu.Unlock()
+
+	if r.cfg.OnCancel != nil {
+		r.cfg.OnCancel(p.EndpointID, requestID, reason)
+	}
+	return nil
+}
+
+// Expire fails every command past its deadline and returns their IDs.
+func (r *Registry) Expire() []string {
+	now := r.now()
+	var expired []*Pending
+	r.mu.Lock()
+	for _, p := range r.pending {
+		if now.After(p.Deadline) {
+			r.finish(p, r.abandoned(p, model.CommandStatusTimeout, "no result before deadline"), ErrTimeout)
+			expired = append(expired, p)
+		}
+	}
+	r.mu.Unlock()
+
+	ids := make([]string, 0, len(expired))
+	for _, p := range expired {
+		utils.Warn("command %s on %s expired without a result", p.RequestID, p.EndpointID)
+		if r.cfg.OnCancel != nil {
+			r.cfg.OnCancel(p.EndpointID, p.RequestID, "deadline exceeded")
+		}
+		ids = append(ids, p.RequestID)
+	}
+	return ids
+}
+
+// Run calls Expire every interval until ctx ends.
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			r.Expire()
+		}
+	}
+}
+
+// Pending returns the commands in flight, optionally for one endpoint.
+func (r *Registry) Pending(endpointID string) []*Pending {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*Pending
+	for _, p := range r.pending {
+		if endpointID == "" || p.EndpointID == endpointID {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Get returns a finished result by request ID.
+func (r *Registry) Get(requestID string) (model.CommandResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res, ok := r.history[requestID]
+	return res, ok
+}
+
+// History returns up to n finished results, newest first. n <= 0 returns
+// all of them.
+func (r *Registry) History(n int) []model.CommandResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n <= 0 || n > len(r.order) {
+		n = len(r.order)
+	}
+	out := make([]model.CommandResult, 0, n)
+	for i := len(r.order) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, r.history[r.order[i]])
+	}
+	return out
+}
+
+// abandoned builds the result for a command that will get no response.
+// Callers hold r.mu.
+func (r *Registry) abandoned(p *Pending, status, reason string) model.CommandResult {
+	return model.CommandResult{
+		EndpointID:   p.EndpointID,
+		Output:       p.output.String(),
+		ErrorMessage: reason,
+		Timestamp:    r.now().UTC().Format(time.RFC3339),
+		RequestID:    p.RequestID,
+		Status:       status,
+		ExitCode:     -1,
+		DurationMs:   r.now().Sub(p.Sent).Milliseconds(),
+	}
+}
+
+// finish moves p to the history. Callers hold r.mu.
+func (r *Registry) finish(p *Pending, res model.CommandResult, err error) {
+	delete(r.pending, p.RequestID)
+	p.result, p.err = res, err
+	close(p.done)
+
+	if _, ok := r.history[p.RequestID]; !ok {
+		r.order = append(r.order, p.RequestID)
+	}
+	r.history[p.RequestID] = res
+	for len(r.order) > r.cfg.HistorySize {
+		delete(r.history, r.order[0])
+		r.order = r.order[1:]
+	}
+}
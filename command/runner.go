@@ -0,0 +1,291 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/runner.go
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// ErrUnsupported is returned when a requested limit or option is not
+// available on this platform.
+var ErrUnsupported = errors.New("command: not supported on this platform")
+
+// DefaultRestrictedEnv is the environment a restricted command starts from.
+var DefaultRestrictedEnv = []string{
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	"LANG=C.UTF-8",
+}
+
+// Limits are resource limits applied to a command (Linux only). Zero
+// values leave the limit unchanged.
+type Limits struct {
+	CPUSeconds  int   `yaml:"cpu_seconds,omitempty" json:"cpu_seconds,omitempty"`
+	MemoryBytes int64 `yaml:"memory_bytes,omitempty" json:"memory_bytes,omitempty"` // address space
+	OpenFiles   int   `yaml:"open_files,omitempty" json:"open_files,omitempty"`
+}
+
+func (l Limits) set() bool {
+	return l.CPUSeconds > 0 || l.MemoryBytes > 0 || l.OpenFiles > 0
+}
+
+// RunnerConfig controls a Runner.
+type RunnerConfig struct {
+	// Shell runs commands given without Args. Default "/bin/sh".
+	Shell string `yaml:"shell,omitempty" json:"shell,omitempty"`
+
+	// DefaultTimeout applies to requests without a Timeout. 0 = none.
+	DefaultTimeout time.Duration `yaml:"default_timeout,omitempty" json:"default_timeout,omitempty"`
+
+	// KillGrace is how long the process group has to exit after SIGTERM
+	// before it is killed. Default 5s.
+	KillGrace time.Duration `yaml:"kill_grace,omitempty" json:"kill_grace,omitempty"`
+
+	// MaxOutput caps captured output in bytes, per stream when
+	// SeparateOutput is set. Default 1 MiB.
+	MaxOutput int `yaml:"max_output,omitempty" json:"max_output,omitempty"`
+
+	// SeparateOutput also captures stdout and stderr on their own.
+	SeparateOutput bool `yaml:"separate_output,omitempty" json:"separate_output,omitempty"`
+
+	// RestrictedEnv starts commands from RestrictedBaseEnv (default
+	// DefaultRestrictedEnv) plus the request's Env, instead of inheriting
+	// the agent's environment.
+	RestrictedEnv     bool     `yaml:"restricted_env,omitempty" json:"restricted_env,omitempty"`
+	RestrictedBaseEnv []string `yaml:"restricted_base_env,omitempty" json:"restricted_base_env,omitempty"`
+
+	Limits Limits `yaml:"limits,omitempty" json:"limits,omitempty"`
+}
+
+// Runner executes commands and reports model.ShellCommandResults. Each
+// command runs in its own process group, which is terminated as a whole on
+// timeout or cancellation.
+type Runner struct {
+	cfg RunnerConfig
+}
+
+// NewRunner creates a Runner. It fails if limits are configured on a
+// platform that cannot apply them.
+func NewRunner(cfg RunnerConfig) (*Runner, error) {
+	if cfg.Shell == "" {
+		cfg.Shell = "/bin/sh"
+	}
+	if cfg.KillGrace <= 0 {
+		cfg.KillGrace = 5 * time.Second
+	}
+	if cfg.MaxOutput <= 0 {
+		cfg.MaxOutput = 1 << 20
+	}
+	if len(cfg.RestrictedBaseEnv) == 0 {
+		cfg.RestrictedBaseEnv = DefaultRestrictedEnv
+	}
+	if cfg.Limits.set() && !limitsSupported {
+		return nil, fmt.Errorf("%w: resource limits", ErrUnsupported)
+	}
+	return &Runner{cfg: cfg}, nil
+}
+
+// Run executes a request. Without Args, CommandData is run by the shell;
+// with Args, CommandData is the program and Args its arguments. If send is
+// not nil, output is streamed through it as it is produced. Execution
+// failures are reported in the result, not as an error.
+func (r *Runner) Run(ctx context.Context, req *model.CommandRequest, send func(*model.CommandOutputChunk) error) *model.ShellCommandResult {
+	res := &model.ShellCommandResult{StartedAt: time.Now()}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = r.cfg.DefaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd, err := r.command(req)
+	if err != nil {
+		res.ExitCode = -1
+		res.ErrorMessage = err.Error()
+		return res
+	}
+
+	streamer := NewOutputStreamer(req.RequestID, send, r.cfg.MaxOutput)
+	var stdout, stderr *cappedBuffer
+	cmd.Stdout, cmd.Stderr = io.Writer(streamer.Stdout()), io.Writer(streamer.Stderr())
+	if r.cfg.SeparateOutput {
+		stdout = &cappedBuffer{max: r.cfg.MaxOutput}
+		stderr = &cappedBuffer{max: r.cfg.MaxOutput}
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, stdout)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, stderr)
+	}
+	if len(req.Stdin) > 0 {
+		cmd.Stdin = bytes.NewReader(req.Stdin)
+	}
+	// Do not let a grandchild holding the output pipes block Wait forever.
+	cmd.WaitDelay = 2 * r.cfg.KillGrace
+
+	if err := cmd.Start(); err != nil {
+		res.ExitCode = -1
+		res.ErrorMessage = fmt.Sprintf("start: %v", err)
+		res.Duration = time.Since(res.StartedAt)
+		return res
+	}
+
+	waitErr := r.wait(ctx, cmd)
+	res.Duration = time.Since(res.StartedAt)
+	res.Output, res.Truncated = streamer.Output()
+	if stdout != nil {
+		res.Stdout, res.Stderr = stdout.String(), stderr.String()
+		res.Truncated = res.Truncated || stdout.truncated || stderr.truncated
+	}
+	res.ExitCode = -1
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		res.TimedOut = true
+		res.ErrorMessage = fmt.Sprintf("timed out after %s", timeout)
+	case ctx.Err() != nil:
+		res.ErrorMessage = "canceled"
+	case waitErr != nil && !isExitError(waitErr):
+		res.ErrorMessage = waitErr.Error()
+	}
+	if err := streamer.Err(); err != nil && res.ErrorMessage == "" {
+		res.ErrorMessage = fmt.Sprintf("streaming output: %v", err)
+	}
+	return res
+}
+
+// wait waits for the command, terminating its process group when ctx ends.
+func (r *Runner) wait(ctx context.Context, cmd *exec.Cmd) error {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	terminate(cmd, false)
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(r.cfg.KillGrace):
+	}
+	terminate(cmd, true)
+	return <-done
+}
+
+// command builds the exec.Cmd for a request.
+func (r *Runner) command(req *model.CommandRequest) (*exec.Cmd, error) {
+	if req.CommandData == "" {
+		return nil, errors.New("empty command")
+	}
+	var argv []string
+	if len(req.Args) == 0 {
+		argv = []string{r.cfg.Shell, "-c", req.CommandData}
+	} else {
+		argv = append([]string{req.CommandData}, req.Args...)
+	}
+	if r.cfg.Limits.set() {
+		argv = wrapLimits(r.cfg.Limits, argv)
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = req.WorkingDir
+	cmd.Env = r.environ(req.Env)
+	setProcessGroup(cmd)
+	if req.RunAsUser != "" {
+		if err := setUser(cmd, req.RunAsUser); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+// environ builds the command environment, with request variables sorted so
+// runs are reproducible.
+func (r *Runner) environ(extra map[string]string) []string {
+	var env []string
+	if r.cfg.RestrictedEnv {
+		env = append(env, r.cfg.RestrictedBaseEnv...)
+	} else {
+		env = os.Environ()
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+extra[k])
+	}
+	return env
+}
+
+func isExitError(err error) bool {
+	var ee *exec.ExitError
+	return errors.As(err, &ee)
+}
+
+// cappedBuffer keeps the first max bytes written to it.
+type cappedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	room := b.max - b.buf.Len()
+	if len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	b.buf.Write(p)
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,253 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/sign.go
+
+package command
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+var (
+	ErrUnsigned     = errors.New("command: request is not signed")
+	ErrBadSignature = errors.New("command: invalid signature")
+	ErrUnknownKey   = errors.New("command: unknown signing key")
+	ErrExpired      = errors.New("command: request expired")
+	ErrReplay       = errors.New("command: nonce already used")
+	ErrWrongAgent   = errors.New("command: request is for another agent")
+	ErrInvalidKey   = errors.New("command: invalid key")
+)
+
+// signingContext prefixes every signed message so a signature over a
+// command can never be mistaken for a signature over anything else.
+const signingContext = "gosight-command-v1"
+
+// CanonicalBytes is the message signed for a request: every field that
+// affects execution, length-prefixed in a fixed order, with Env sorted by
+// key. Signature itself is excluded.
+func CanonicalBytes(r *model.CommandRequest) []byte {
+	var b []byte
+	str := func(s string) {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	num := func(n int64) {
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	str(signingContext)
+	str(r.AgentID)
+	str(r.CommandType)
+	str(r.CommandData)
+	num(int64(len(r.Args)))
+	for _, a := range r.Args {
+		str(a)
+	}
+	str(r.RequestID)
+	num(int64(r.Timeout / time.Second)) // the wire carries whole seconds
+	keys := make([]string, 0, len(r.Env))
+	for k := range r.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	num(int64(len(keys)))
+	for _, k := range keys {
+		str(k)
+		str(r.Env[k])
+	}
+	str(r.WorkingDir)
+	str(r.RunAsUser)
+	str(string(r.Stdin))
+	if r.StreamOutput {
+		num(1)
+	} else {
+		num(0)
+	}
+	str(r.RequestedBy)
+	str(r.Nonce)
+	num(unixNano(r.IssuedAt))
+	num(unixNano(r.ExpiresAt))
+	str(r.KeyID)
+	return b
+}
+
+// Signer signs requests on the server.
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// NewSigner creates a Signer. Signed requests expire after ttl (default 5m).
+func NewSigner(key ed25519.PrivateKey, keyID string, ttl time.Duration) (*Signer, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: private key must be %d bytes", ErrInvalidKey, ed25519.PrivateKeySize)
+	}
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &Signer{key: key, keyID: keyID, ttl: ttl, now: time.Now}, nil
+}
+
+// Sign sets Nonce, IssuedAt, ExpiresAt and KeyID on a request and signs it.
+// The request's Timeout is truncated to whole seconds first, as that is
+// what the agent receives. Sign must be the last change made to a request.
+func (s *Signer) Sign(r *model.CommandRequest) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("command: nonce: %w", err)
+	}
+	now := s.now()
+	r.Timeout = r.Timeout.Truncate(time.Second)
+	r.Nonce = hex.EncodeToString(nonce)
+	r.IssuedAt = now
+	r.ExpiresAt = now.Add(s.ttl)
+	r.KeyID = s.keyID
+	r.Signature = ed25519.Sign(s.key, CanonicalBytes(r))
+	return nil
+}
+
+// Verifier checks request signatures on the agent against pinned public
+// keys, and rejects expired or replayed requests. It is safe for
+// concurrent use.
+type Verifier struct {
+	keys    map[string]ed25519.PublicKey
+	agentID string
+	skew    time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]time.Time // nonce -> expiry
+}
+
+// NewVerifier pins the server's public keys by key ID. agentID is the
+// local agent's ID; requests signed for any other agent are rejected, so a
+// command cannot be replayed on every agent that pins the same key. skew is
+// the clock difference tolerated between server and agent (default 30s).
+func NewVerifier(keys map[string]ed25519.PublicKey, agentID string, skew time.Duration) (*Verifier, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no public keys", ErrInvalidKey)
+	}
+	if agentID == "" {
+		return nil, errors.New("command: verifier needs the local agent ID")
+	}
+	for id, k := range keys {
+		if len(k) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: public key %q must be %d bytes", ErrInvalidKey, id, ed25519.PublicKeySize)
+		}
+	}
+	if skew <= 0 {
+		skew = 30 * time.Second
+	}
+	return &Verifier{keys: keys, agentID: agentID, skew: skew, now: time.Now, nonces: map[string]time.Time{}}, nil
+}
+
+// Verify checks a request's signature, target agent, validity window and
+// nonce. A nonce is only consumed by a request that passes every other
+// check.
+func (v *Verifier) Verify(r *model.CommandRequest) error {
+	if len(r.Signature) == 0 {
+		return ErrUnsigned
+	}
+	key, ok := v.keys[r.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, r.KeyID)
+	}
+	if !ed25519.Verify(key, CanonicalBytes(r), r.Signature) {
+		return ErrBadSignature
+	}
+	if r.AgentID != v.agentID {
+		return fmt.Errorf("%w: signed for %q", ErrWrongAgent, r.AgentID)
+	}
+	if r.Nonce == "" || r.IssuedAt.IsZero() || r.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: missing nonce or validity window", ErrBadSignature)
+	}
+	now := v.now()
+	if now.Add(v.skew).Before(r.IssuedAt) || now.Add(-v.skew).After(r.ExpiresAt) {
+		return fmt.Errorf("%w: valid %s to %s", ErrExpired, r.IssuedAt.Format(time.RFC3339), r.ExpiresAt.Format(time.RFC3339))
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for n, exp := range v.nonces {
+		if now.Add(-v.skew).After(exp) {
+			delete(v.nonces, n)
+		}
+	}
+	if _, used := v.nonces[r.Nonce]; used {
+		return ErrReplay
+	}
+	v.nonces[r.Nonce] = r.ExpiresAt
+	return nil
+}
+
+// GenerateKey creates a new Ed25519 key pair for signing commands.
+func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// ParsePublicKey decodes a 32 byte Ed25519 public key given in base64 or
+// hex, the form pinned in agent configuration.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := decodeKey(s)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: expected %d byte base64 or hex public key", ErrInvalidKey, ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// ParsePrivateKey decodes an Ed25519 private key or 32 byte seed given in
+// base64 or hex.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := decodeKey(s)
+	switch {
+	case err != nil:
+	case len(b) == ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	case len(b) == ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	}
+	return nil, fmt.Errorf("%w: expected base64 or hex private key or seed", ErrInvalidKey)
+}
+
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
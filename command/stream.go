@@ -0,0 +1,135 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/command/stream.go
+
+package command
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// DefaultChunkSize is the largest chunk an OutputStreamer sends.
+const DefaultChunkSize = 32 * 1024
+
+// OutputStreamer turns a running command's stdout and stderr into ordered
+// CommandOutputChunks while also collecting the combined output for the
+// final response. Use Stdout and Stderr as the command's writers. It is
+// safe for concurrent use.
+type OutputStreamer struct {
+	requestID string
+	send      func(*model.CommandOutputChunk) error
+	chunkSize int
+	maxOutput int
+
+	mu        sync.Mutex
+	seq       int64
+	combined  bytes.Buffer
+	truncated bool
+	err       error
+}
+
+// NewOutputStreamer creates a streamer that passes chunks to send. A nil
+// send only collects output. maxOutput caps the collected output (0 =
+// unlimited); streaming is not capped.
+func NewOutputStreamer(requestID string, send func(*model.CommandOutputChunk) error, maxOutput int) *OutputStreamer {
+	return &OutputStreamer{requestID: requestID, send: send, chunkSize: DefaultChunkSize, maxOutput: maxOutput}
+}
+
+// Stdout returns the writer for standard output.
+func (s *OutputStreamer) Stdout() *StreamWriter {
+	return &StreamWriter{s: s, stream: model.StreamStdout}
+}
+
+// Stderr returns the writer for standard error.
+func (s *OutputStreamer) Stderr() *StreamWriter {
+	return &StreamWriter{s: s, stream: model.StreamStderr}
+}
+
+// Output returns the combined output collected so far and whether it was
+// truncated at maxOutput.
+func (s *OutputStreamer) Output() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.combined.String(), s.truncated
+}
+
+// Err returns the first error returned by send. Once send fails no more
+// chunks are sent, but output is still collected.
+func (s *OutputStreamer) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *OutputStreamer) write(stream string, p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxOutput <= 0 || s.combined.Len()+len(p) <= s.maxOutput {
+		s.combined.Write(p)
+	} else {
+		if room := s.maxOutput - s.combined.Len(); room > 0 {
+			s.combined.Write(p[:room])
+		}
+		s.truncated = true
+	}
+
+	if s.send == nil || s.err != nil {
+		return
+	}
+	for len(p) > 0 {
+		n := len(p)
+		if n > s.chunkSize {
+			n = s.chunkSize
+		}
+		chunk := &model.CommandOutputChunk{
+			RequestID: s.requestID,
+			Stream:    stream,
+			Data:      append([]byte(nil), p[:n]...),
+			Sequence:  s.seq,
+			Timestamp: time.Now(),
+		}
+		if err := s.send(chunk); err != nil {
+			s.err = err
+			return
+		}
+		s.seq++
+		p = p[n:]
+	}
+}
+
+// StreamWriter is the io.Writer for one output stream of an OutputStreamer.
+type StreamWriter struct {
+	s      *OutputStreamer
+	stream string
+}
+
+// Write records p and sends it as one or more chunks. It never fails, so a
+// broken stream does not kill the command it is attached to.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.s.write(w.stream, p)
+	return len(p), nil
+}
@@ -0,0 +1,395 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/correlate/correlate.go
+
+// Package correlate links logs, spans and metric exemplars that share a
+// trace ID. An Index keeps recent telemetry for a retention period and
+// answers "everything about this trace" queries. Logs that arrive without a
+// trace ID can be enriched from the spans that were active in the same
+// process or container when the log was written.
+package correlate
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/traces"
+)
+
+// FieldInferred is set to "true" in Fields of logs whose trace and span IDs
+// were filled in by Enrich rather than reported by the source.
+const FieldInferred = "trace_id_inferred"
+
+// Config controls an Index.
+type Config struct {
+	// Retention is how long telemetry is kept, measured against the newest
+	// timestamp seen. Default 1h.
+	Retention time.Duration `yaml:"retention,omitempty" json:"retention,omitempty"`
+
+	// Slack widens span intervals when matching logs to spans, to absorb
+	// clock skew between the log and trace pipelines.
+	Slack time.Duration `yaml:"slack,omitempty" json:"slack,omitempty"`
+
+	// Enrich fills in missing trace IDs on logs added to the index.
+	Enrich bool `yaml:"enrich,omitempty" json:"enrich,omitempty"`
+}
+
+// ExemplarRef is an exemplar together with the series it was recorded on.
+type ExemplarRef struct {
+	Namespace    string            `json:"namespace,omitempty"`
+	SubNamespace string            `json:"subnamespace,omitempty"`
+	Metric       string            `json:"metric"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Exemplar     model.Exemplar    `json:"exemplar"`
+}
+
+// Correlation is everything known about one trace.
+type Correlation struct {
+	TraceID   string            `json:"trace_id"`
+	Logs      []model.LogEntry  `json:"logs,omitempty"`
+	Spans     []model.TraceSpan `json:"spans,omitempty"`
+	Exemplars []ExemplarRef     `json:"exemplars,omitempty"`
+}
+
+// Index correlates telemetry by trace ID. It is safe for concurrent use.
+type Index struct {
+	cfg Config
+
+	mu        sync.RWMutex
+	logs      map[string][]model.LogEntry
+	spans     map[string][]model.TraceSpan
+	exemplars map[string][]ExemplarRef
+	active    map[string][]*model.TraceSpan // spans by process or container key
+	newest    time.Time
+	pruned    time.Time
+}
+
+// New creates an Index.
+func New(cfg Config) *Index {
+	if cfg.Retention <= 0 {
+		cfg.Retention = time.Hour
+	}
+	return &Index{
+		cfg:       cfg,
+		logs:      map[string][]model.LogEntry{},
+		spans:     map[string][]model.TraceSpan{},
+		exemplars: map[string][]ExemplarRef{},
+		active:    map[string][]*model.TraceSpan{},
+	}
+}
+
+// AddLog indexes a log entry, first enriching it when Config.Enrich is set.
+// Entries without a trace ID are not kept.
+func (x *Index) AddLog(e *model.LogEntry) {
+	if e.TraceID == "" && x.cfg.Enrich {
+		x.Enrich(e)
+	}
+	if e.TraceID == "" {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.logs[e.TraceID] = append(x.logs[e.TraceID], *e)
+	x.observe(e.Timestamp)
+}
+
+// AddLogPayload indexes every entry of a payload; entries without Meta are
+// matched using the payload's.
+func (x *Index) AddLogPayload(p *model.LogPayload) {
+	for i := range p.Logs {
+		e := &p.Logs[i]
+		if e.Meta == nil && p.Meta != nil {
+			e.Meta = p.Meta
+			x.AddLog(e)
+			e.Meta = nil
+			continue
+		}
+		x.AddLog(e)
+	}
+}
+
+// AddSpan indexes a span and makes it available to Enrich.
+func (x *Index) AddSpan(s model.TraceSpan) {
+	if s.TraceID == "" {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.spans[s.TraceID] = append(x.spans[s.TraceID], s)
+	for _, k := range spanKeys(&s) {
+		x.active[k] = append(x.active[k], &s)
+	}
+	x.observe(traces.End(&s))
+}
+
+// AddTracePayload indexes every span of a payload. Spans without Meta
+// inherit the payload's.
+func (x *Index) AddTracePayload(p *model.TracePayload) {
+	for _, s := range p.Traces {
+		if s.Meta == nil {
+			s.Meta = p.Meta
+		}
+		x.AddSpan(s)
+	}
+}
+
+// AddMetric indexes the exemplars of a metric that carry a trace ID.
+func (x *Index) AddMetric(m *model.Metric) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, dp := range m.DataPoints {
+		for _, ex := range dp.Exemplars {
+			if ex.TraceID == "" {
+				continue
+			}
+			x.exemplars[ex.TraceID] = append(x.exemplars[ex.TraceID], ExemplarRef{
+				Namespace:    m.Namespace,
+				SubNamespace: m.SubNamespace,
+				Metric:       m.Name,
+				Attributes:   dp.Attributes,
+				Exemplar:     ex,
+			})
+			x.observe(ex.Timestamp)
+		}
+	}
+}
+
+// AddMetricPayload indexes the exemplars of every metric in a payload.
+func (x *Index) AddMetricPayload(p *model.MetricPayload) {
+	for i := range p.Metrics {
+		x.AddMetric(&p.Metrics[i])
+	}
+}
+
+// Lookup returns the logs, spans and exemplars of a trace within [from, to],
+// each ordered by time. A zero from or to leaves that side open.
+func (x *Index) Lookup(traceID string, from, to time.Time) Correlation {
+	in := func(t time.Time) bool {
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+	}
+	c := Correlation{TraceID: traceID}
+
+	x.mu.RLock()
+	for _, e := range x.logs[traceID] {
+		if in(e.Timestamp) {
+			c.Logs = append(c.Logs, e)
+		}
+	}
+	for _, s := range x.spans[traceID] {
+		// A span is in range if it overlaps the window at all.
+		if (to.IsZero() || !s.StartTime.After(to)) && (from.IsZero() || !traces.End(&s).Before(from)) {
+			c.Spans = append(c.Spans, s)
+		}
+	}
+	for _, ex := range x.exemplars[traceID] {
+		if in(ex.Exemplar.Timestamp) {
+			c.Exemplars = append(c.Exemplars, ex)
+		}
+	}
+	x.mu.RUnlock()
+
+	sort.SliceStable(c.Logs, func(i, j int) bool { return c.Logs[i].Timestamp.Before(c.Logs[j].Timestamp) })
+	sort.SliceStable(c.Spans, func(i, j int) bool { return c.Spans[i].StartTime.Before(c.Spans[j].StartTime) })
+	sort.SliceStable(c.Exemplars, func(i, j int) bool {
+		return c.Exemplars[i].Exemplar.Timestamp.Before(c.Exemplars[j].Exemplar.Timestamp)
+	})
+	return c
+}
+
+// Enrich sets TraceID and SpanID on a log entry that lacks them, using the
+// innermost span that was active at the entry's timestamp in the same
+// process (host and PID) or container. It reports whether a span was found.
+func (x *Index) Enrich(e *model.LogEntry) bool {
+	if e.TraceID != "" || e.Timestamp.IsZero() {
+		return false
+	}
+	x.mu.RLock()
+	var best *model.TraceSpan
+	for _, k := range logKeys(e) {
+		for _, s := range x.active[k] {
+			if e.Timestamp.Before(s.StartTime.Add(-x.cfg.Slack)) || e.Timestamp.After(traces.End(s).Add(x.cfg.Slack)) {
+				continue
+			}
+			// Prefer the most deeply nested span: the one that started last.
+			if best == nil || s.StartTime.After(best.StartTime) {
+				best = s
+			}
+		}
+	}
+	var traceID, spanID string
+	if best != nil {
+		traceID, spanID = best.TraceID, best.SpanID
+	}
+	x.mu.RUnlock()
+
+	if traceID == "" {
+		return false
+	}
+	e.TraceID, e.SpanID = traceID, spanID
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	e.Fields[FieldInferred] = "true"
+	return true
+}
+
+// Len returns the number of traces with indexed telemetry.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	ids := map[string]struct{}{}
+	for id := range x.logs {
+		ids[id] = struct{}{}
+	}
+	for id := range x.spans {
+		ids[id] = struct{}{}
+	}
+	for id := range x.exemplars {
+		ids[id] = struct{}{}
+	}
+	return len(ids)
+}
+
+// Prune drops everything that ended before cutoff.
+func (x *Index) Prune(cutoff time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.prune(cutoff)
+}
+
+// observe tracks the newest timestamp and prunes a quarter retention at a
+// time. Callers hold x.mu.
+func (x *Index) observe(t time.Time) {
+	if t.After(x.newest) {
+		x.newest = t
+	}
+	if x.newest.Sub(x.pruned) < x.cfg.Retention/4 {
+		return
+	}
+	x.prune(x.newest.Add(-x.cfg.Retention))
+	x.pruned = x.newest
+}
+
+func (x *Index) prune(cutoff time.Time) {
+	for id, logs := range x.logs {
+		kept := logs[:0]
+		for _, e := range logs {
+			if !e.Timestamp.Before(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(x.logs, id)
+		} else {
+			x.logs[id] = kept
+		}
+	}
+	for id, spans := range x.spans {
+		// Spans of a trace are dropped together once the whole trace has
+		// ended, so late logs can still be matched to its outer spans.
+		live := false
+		for i := range spans {
+			if !traces.End(&spans[i]).Before(cutoff) {
+				live = true
+				break
+			}
+		}
+		if !live {
+			delete(x.spans, id)
+		}
+	}
+	for k, spans := range x.active {
+		kept := spans[:0]
+		for _, s := range spans {
+			if _, ok := x.spans[s.TraceID]; ok {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			delete(x.active, k)
+		} else {
+			x.active[k] = kept
+		}
+	}
+	for id, refs := range x.exemplars {
+		kept := refs[:0]
+		for _, r := range refs {
+			if !r.Exemplar.Timestamp.Before(cutoff) {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) == 0 {
+			delete(x.exemplars, id)
+		} else {
+			x.exemplars[id] = kept
+		}
+	}
+}
+
+// spanKeys are the process and container keys a span can be matched on.
+func spanKeys(s *model.TraceSpan) []string {
+	host := s.HostID
+	container := s.ResourceAttrs["container.id"]
+	if s.Meta != nil {
+		if host == "" {
+			host = s.Meta.HostID
+		}
+		if container == "" {
+			container = s.Meta.ContainerID
+		}
+	}
+	pid := s.ResourceAttrs["process.pid"]
+	if pid == "" {
+		pid = s.Attributes["process.pid"]
+	}
+	return matchKeys(host, pid, container)
+}
+
+// logKeys are the process and container keys of a log entry.
+func logKeys(e *model.LogEntry) []string {
+	var host, container string
+	if e.Meta != nil {
+		host, container = e.Meta.HostID, e.Meta.ContainerID
+	}
+	if container == "" {
+		container = e.Fields["container_id"]
+	}
+	pid := ""
+	if e.PID > 0 {
+		pid = strconv.Itoa(e.PID)
+	}
+	return matchKeys(host, pid, container)
+}
+
+func matchKeys(host, pid, container string) []string {
+	var keys []string
+	if pid != "" && pid != "0" {
+		keys = append(keys, "pid:"+host+":"+pid)
+	}
+	if container != "" {
+		keys = append(keys, "container:"+container)
+	}
+	return keys
+}
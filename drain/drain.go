@@ -0,0 +1,504 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/drain/drain.go
+
+// Package drain mines log templates online with the Drain algorithm (He et
+// al., ICWS 2017). Each message is routed through a fixed-depth parse tree by
+// token count and leading tokens, then merged into the most similar cluster
+// at the leaf, with differing tokens replaced by a wildcard. Clusters keep
+// per-bucket counts so new patterns and rate spikes can be alerted on.
+package drain
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+// Wildcard replaces variable tokens in templates.
+const Wildcard = "<*>"
+
+// Fields set on processed entries.
+const (
+	FieldPatternID = "pattern_id"
+	FieldTemplate  = "pattern"
+)
+
+// DefaultMasks replace obviously variable tokens before clustering.
+var DefaultMasks = []string{
+	`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`, // UUID
+	`\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`,                                            // IPv4[:port]
+	`\b0x[0-9a-fA-F]+\b`,                                                              // hex
+	`\b[0-9a-fA-F]{16,}\b`,                                                            // long hex ids
+	`(?:^|[\s=:(\[])[-+]?\d+(?:\.\d+)?(?:ms|s|us|ns|%|[kKMG]i?B)?\b`,                  // numbers
+}
+
+// Config tunes the miner.
+type Config struct {
+	Depth               int      // parse tree depth counting root, length and leaf layers; default 4 (one token layer)
+	SimilarityThreshold float64  // minimum share of matching tokens to join a cluster; default 0.4
+	MaxChildren         int      // children per tree node before routing to the wildcard; default 100
+	MaxClusters         int      // least recently seen clusters are evicted beyond this; 0 = unlimited
+	ExtraDelimiters     []string // split tokens on these in addition to whitespace
+	Masks               []string // extra regexes replaced by the wildcard
+	NoDefaultMasks      bool     // do not apply DefaultMasks
+
+	BucketWidth time.Duration // width of count buckets; default 1m
+	Buckets     int           // buckets of history kept per cluster; default 60
+
+	// ReportNew records an event for every new pattern, see DrainEvents.
+	ReportNew bool
+}
+
+// Cluster is a mined template.
+type Cluster struct {
+	ID        string    `json:"id"`
+	Template  string    `json:"template"`
+	Count     uint64    `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Bucket is the number of messages matching a cluster in one interval.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count uint64    `json:"count"`
+}
+
+// Miner clusters messages. It is safe for concurrent use.
+type Miner struct {
+	cfg   Config
+	masks []*regexp.Regexp
+
+	mu       sync.Mutex
+	root     *node
+	clusters map[string]*cluster
+	lru      *list.List // front = most recently seen
+	events   []model.EventEntry
+}
+
+type cluster struct {
+	Cluster
+	tokens  []string
+	leaf    *node
+	elem    *list.Element
+	buckets map[int64]uint64
+}
+
+type node struct {
+	children map[string]*node
+	clusters []*cluster
+}
+
+// NewMiner creates a miner. It fails only if a mask does not compile.
+func NewMiner(cfg Config) (*Miner, error) {
+	if cfg.Depth < 3 {
+		cfg.Depth = 4
+	}
+	if cfg.SimilarityThreshold <= 0 {
+		cfg.SimilarityThreshold = 0.4
+	}
+	if cfg.MaxChildren <= 0 {
+		cfg.MaxChildren = 100
+	}
+	if cfg.BucketWidth <= 0 {
+		cfg.BucketWidth = time.Minute
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = 60
+	}
+
+	m := &Miner{
+		cfg:      cfg,
+		root:     newNode(),
+		clusters: map[string]*cluster{},
+		lru:      list.New(),
+	}
+	patterns := cfg.Masks
+	if !cfg.NoDefaultMasks {
+		patterns = append(append([]string(nil), DefaultMasks...), cfg.Masks...)
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("drain: invalid mask %q: %w", p, err)
+		}
+		m.masks = append(m.masks, re)
+	}
+	return m, nil
+}
+
+func newNode() *node {
+	return &node{children: map[string]*node{}}
+}
+
+// Add clusters one message seen at ts and reports whether it created a new
+// cluster.
+func (m *Miner) Add(message string, ts time.Time) (Cluster, bool) {
+	tokens := m.tokenize(message)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	leaf := m.leafFor(tokens)
+	c := m.bestMatch(leaf, tokens)
+	created := c == nil
+	if created {
+		c = &cluster{
+			Cluster: Cluster{
+				ID:        m.newID(tokens),
+				Template:  strings.Join(tokens, " "),
+				FirstSeen: ts,
+			},
+			tokens:  tokens,
+			leaf:    leaf,
+			buckets: map[int64]uint64{},
+		}
+		leaf.clusters = append(leaf.clusters, c)
+		m.clusters[c.ID] = c
+		c.elem = m.lru.PushFront(c)
+		m.evict()
+		if m.cfg.ReportNew {
+			m.reportNew(c, message, ts)
+		}
+	} else {
+		for i, t := range c.tokens {
+			if t != tokens[i] && t != Wildcard {
+				c.tokens[i] = Wildcard
+			}
+		}
+		c.Template = strings.Join(c.tokens, " ")
+		m.lru.MoveToFront(c.elem)
+	}
+
+	c.Count++
+	if ts.After(c.LastSeen) {
+		c.LastSeen = ts
+	}
+	bucket := ts.UnixNano() / int64(m.cfg.BucketWidth)
+	c.buckets[bucket]++
+	for b := range c.buckets {
+		if b <= bucket-int64(m.cfg.Buckets) {
+			delete(c.buckets, b)
+		}
+	}
+	return c.Cluster, created
+}
+
+// Process clusters an entry's Message (or Body) and records the result in
+// Fields under FieldPatternID and FieldTemplate.
+func (m *Miner) Process(e *model.LogEntry) (Cluster, bool) {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Body
+	}
+	ts := e.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	c, created := m.Add(msg, ts)
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	e.Fields[FieldPatternID] = c.ID
+	e.Fields[FieldTemplate] = c.Template
+	return c, created
+}
+
+// ProcessPayload processes every entry and returns the clusters that were
+// created by this payload.
+func (m *Miner) ProcessPayload(p *model.LogPayload) []Cluster {
+	var created []Cluster
+	for i := range p.Logs {
+		if c, ok := m.Process(&p.Logs[i]); ok {
+			created = append(created, c)
+		}
+	}
+	return created
+}
+
+// tokenize masks variables and splits a message into tokens.
+func (m *Miner) tokenize(message string) []string {
+	for _, re := range m.masks {
+		message = re.ReplaceAllStringFunc(message, func(match string) string {
+			// Keep the delimiter some masks consume at the front.
+			if strings.ContainsAny(match[:1], " \t=:([") {
+				return match[:1] + Wildcard
+			}
+			return Wildcard
+		})
+	}
+	for _, d := range m.cfg.ExtraDelimiters {
+		message = strings.ReplaceAll(message, d, " ")
+	}
+	tokens := strings.Fields(message)
+	if len(tokens) == 0 {
+		tokens = []string{""}
+	}
+	return tokens
+}
+
+// leafFor walks, creating as needed, the tree path for a token sequence:
+// token count, then Depth-3 leading tokens, with numeric tokens routed to
+// the wildcard.
+func (m *Miner) leafFor(tokens []string) *node {
+	n := child(m.root, strconv.Itoa(len(tokens)), m.cfg.MaxChildren)
+	for i := 0; i < m.cfg.Depth-3 && i < len(tokens); i++ {
+		key := tokens[i]
+		if hasDigit(key) {
+			key = Wildcard
+		}
+		n = child(n, key, m.cfg.MaxChildren)
+	}
+	return n
+}
+
+// child returns n's child for key, routing to the wildcard child once n is
+// full.
+func child(n *node, key string, maxChildren int) *node {
+	if c, ok := n.children[key]; ok {
+		return c
+	}
+	if len(n.children) >= maxChildren-1 && key != Wildcard {
+		key = Wildcard
+		if c, ok := n.children[key]; ok {
+			return c
+		}
+	}
+	c := newNode()
+	n.children[key] = c
+	return c
+}
+
+// bestMatch returns the most similar cluster at a leaf above the threshold.
+// A masked token matches a wildcard in the template, so messages made up
+// mostly of masked variables still find their cluster. Ties go to the
+// template with more wildcards, as in Drain3.
+func (m *Miner) bestMatch(leaf *node, tokens []string) *cluster {
+	var (
+		best      *cluster
+		bestSim   = -1.0
+		bestWilds = -1
+	)
+	for _, c := range leaf.clusters {
+		if len(c.tokens) != len(tokens) {
+			continue
+		}
+		same, wilds := 0, 0
+		for i, t := range c.tokens {
+			if t == Wildcard {
+				wilds++
+			}
+			if t == tokens[i] {
+				same++
+			}
+		}
+		sim := float64(same) / float64(len(tokens))
+		if sim > bestSim || sim == bestSim && wilds > bestWilds {
+			best, bestSim, bestWilds = c, sim, wilds
+		}
+	}
+	if best == nil || bestSim < m.cfg.SimilarityThreshold {
+		return nil
+	}
+	return best
+}
+
+// evict drops least recently seen clusters beyond MaxClusters.
+func (m *Miner) evict() {
+	for m.cfg.MaxClusters > 0 && m.lru.Len() > m.cfg.MaxClusters {
+		m.remove(m.lru.Back().Value.(*cluster))
+	}
+}
+
+func (m *Miner) remove(c *cluster) {
+	m.lru.Remove(c.elem)
+	delete(m.clusters, c.ID)
+	for i, lc := range c.leaf.clusters {
+		if lc == c {
+			c.leaf.clusters = append(c.leaf.clusters[:i], c.leaf.clusters[i+1:]...)
+			break
+		}
+	}
+}
+
+func (m *Miner) reportNew(c *cluster, message string, ts time.Time) {
+	msg := fmt.Sprintf("new log pattern %s: %s", c.ID, c.Template)
+	utils.Debug("drain: %s", msg)
+	m.events = append(m.events, model.EventEntry{
+		ID:        utils.NewUUID(),
+		Timestamp: ts,
+		Level:     "info",
+		Type:      "system",
+		Category:  "log",
+		Message:   msg,
+		Source:    "drain",
+		Scope:     "system",
+		Meta: map[string]string{
+			FieldPatternID: c.ID,
+			FieldTemplate:  c.Template,
+			"example":      utils.Truncate(message, 512),
+		},
+	})
+}
+
+// DrainEvents returns and clears the events recorded for new patterns.
+func (m *Miner) DrainEvents() []model.EventEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.events
+	m.events = nil
+	return out
+}
+
+// Clusters returns every cluster, most frequent first.
+func (m *Miner) Clusters() []Cluster {
+	m.mu.Lock()
+	out := make([]Cluster, 0, len(m.clusters))
+	for _, c := range m.clusters {
+		out = append(out, c.Cluster)
+	}
+	m.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Cluster returns a cluster by ID.
+func (m *Miner) Cluster(id string) (Cluster, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.clusters[id]
+	if !ok {
+		return Cluster{}, false
+	}
+	return c.Cluster, true
+}
+
+// Counts returns a cluster's per-bucket counts, oldest first, for the
+// retained history ending with the bucket that contains now. Empty buckets
+// are included.
+func (m *Miner) Counts(id string, now time.Time) []Bucket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.clusters[id]
+	if !ok {
+		return nil
+	}
+	width := int64(m.cfg.BucketWidth)
+	last := now.UnixNano() / width
+	first := last - int64(m.cfg.Buckets) + 1
+	if seen := c.FirstSeen.UnixNano() / width; seen > first {
+		first = seen
+	}
+	out := make([]Bucket, 0, last-first+1)
+	for b := first; b <= last; b++ {
+		out = append(out, Bucket{Start: time.Unix(0, b*width).UTC(), Count: c.buckets[b]})
+	}
+	return out
+}
+
+// Spike reports a cluster whose latest complete bucket is well above its
+// average over the preceding buckets.
+type Spike struct {
+	Cluster  Cluster `json:"cluster"`
+	Count    uint64  `json:"count"`    // messages in the latest complete bucket
+	Baseline float64 `json:"baseline"` // mean of the earlier retained buckets
+}
+
+// Spikes compares each cluster's last complete bucket before now with the
+// mean of the retained buckets before it. A cluster spikes when that count
+// is at least minCount and more than factor times the baseline. Clusters
+// without history before the bucket are skipped; new patterns are reported
+// by Add instead.
+func (m *Miner) Spikes(now time.Time, factor float64, minCount uint64) []Spike {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	width := int64(m.cfg.BucketWidth)
+	cur := now.UnixNano()/width - 1
+	var out []Spike
+	for _, c := range m.clusters {
+		count := c.buckets[cur]
+		if count < minCount {
+			continue
+		}
+		first := cur - int64(m.cfg.Buckets) + 1
+		if seen := c.FirstSeen.UnixNano() / width; seen > first {
+			first = seen
+		}
+		if first >= cur {
+			continue
+		}
+		var sum uint64
+		for b := first; b < cur; b++ {
+			sum += c.buckets[b]
+		}
+		baseline := float64(sum) / float64(cur-first)
+		if float64(count) > factor*baseline {
+			out = append(out, Spike{Cluster: c.Cluster, Count: count, Baseline: baseline})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Cluster.ID < out[j].Cluster.ID })
+	return out
+}
+
+// newID returns the ID for a cluster starting with tokens: clusterID, with a
+// suffix if a live cluster already holds it. An evicted cluster's ID is free
+// again, so a template that returns gets its old ID back.
+func (m *Miner) newID(tokens []string) string {
+	base := clusterID(tokens)
+	id := base
+	for n := 2; m.clusters[id] != nil; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// clusterID derives a stable ID from the template a cluster starts with.
+func clusterID(tokens []string) string {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(tokens, " ")))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func hasDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			return true
+		}
+	}
+	return false
+}
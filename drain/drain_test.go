@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/drain/drain_test.go
+
+package drain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddMasked(t *testing.T) {
+	m, err := NewMiner(Config{ReportNew: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1700000000, 0)
+	var first Cluster
+	for i := 0; i < 3; i++ {
+		c, created := m.Add("10.0.0.1 10.0.0.2 10.0.0.3 refused", ts.Add(time.Duration(i)*time.Second))
+		if i == 0 {
+			first = c
+			if !created {
+				t.Fatal("first message did not create a cluster")
+			}
+			continue
+		}
+		if created {
+			t.Errorf("message %d created a new cluster", i+1)
+		}
+		if c.ID != first.ID {
+			t.Errorf("message %d joined %s, want %s", i+1, c.ID, first.ID)
+		}
+		if c.Count != uint64(i+1) {
+			t.Errorf("message %d: count = %d, want %d", i+1, c.Count, i+1)
+		}
+	}
+	if got := len(m.Clusters()); got != 1 {
+		t.Errorf("%d clusters, want 1", got)
+	}
+	if got := len(m.DrainEvents()); got != 1 {
+		t.Errorf("%d new-pattern events, want 1", got)
+	}
+	if c, _ := m.Cluster(first.ID); c.Template != "<*> <*> <*> refused" {
+		t.Errorf("template = %q", c.Template)
+	}
+}
+
+func TestAddMerges(t *testing.T) {
+	m, err := NewMiner(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1700000000, 0)
+	a, _ := m.Add("user alice logged in from web", ts)
+	b, created := m.Add("user bob logged in from web", ts)
+	if created || b.ID != a.ID {
+		t.Fatalf("second message created=%v id=%s, want it to join %s", created, b.ID, a.ID)
+	}
+	if b.Template != "user <*> logged in from web" || b.Count != 2 {
+		t.Errorf("cluster = %+v", b)
+	}
+	if _, created := m.Add("disk full on volume data", ts); !created {
+		t.Error("unrelated message joined an existing cluster")
+	}
+}
@@ -0,0 +1,264 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/histogram/exponential.go
+
+package histogram
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// MaxExponentialBuckets caps the number of buckets per side that a conversion
+// to the exponential layout may produce. Callers should lower the scale if a
+// conversion fails with this limit.
+const MaxExponentialBuckets = 4096
+
+// ExponentialBuckets holds one side (positive or negative) of an exponential
+// histogram. BucketCounts[i] covers (base^(Offset+i), base^(Offset+i+1)].
+type ExponentialBuckets struct {
+	Offset       int32    `json:"offset"`
+	BucketCounts []uint64 `json:"bucket_counts,omitempty"`
+}
+
+// ExponentialHistogram mirrors the OTLP exponential histogram data point.
+// The bucket base is 2^(2^-Scale).
+type ExponentialHistogram struct {
+	Scale         int32              `json:"scale"`
+	ZeroCount     uint64             `json:"zero_count"`
+	ZeroThreshold float64            `json:"zero_threshold,omitempty"`
+	Positive      ExponentialBuckets `json:"positive"`
+	Negative      ExponentialBuckets `json:"negative"`
+	Count         uint64             `json:"count"`
+	Sum           float64            `json:"sum"`
+}
+
+// Base returns the bucket growth factor for the histogram scale.
+func (e *ExponentialHistogram) Base() float64 {
+	return math.Exp2(math.Exp2(-float64(e.Scale)))
+}
+
+// ExponentialIndex returns the bucket index that contains the positive value v
+// at the given scale.
+func ExponentialIndex(v float64, scale int32) int32 {
+	idx := math.Ceil(math.Log2(v)*math.Exp2(float64(scale))) - 1
+	// Values close to a bucket edge can land one bucket off due to floating
+	// point error; correct against the computed edges.
+	if exponentialLower(int32(idx), scale) >= v {
+		idx--
+	} else if exponentialLower(int32(idx)+1, scale) < v {
+		idx++
+	}
+	return int32(idx)
+}
+
+// exponentialLower returns the exclusive lower edge of bucket idx.
+func exponentialLower(idx, scale int32) float64 {
+	return math.Exp2(float64(idx) * math.Exp2(-float64(scale)))
+}
+
+// spans converts the exponential histogram into finite spans.
+func (e *ExponentialHistogram) spans() []span {
+	var out []span
+	if e.ZeroCount > 0 {
+		out = append(out, span{lo: -e.ZeroThreshold, hi: e.ZeroThreshold, count: float64(e.ZeroCount)})
+	}
+	for i, c := range e.Positive.BucketCounts {
+		if c == 0 {
+			continue
+		}
+		idx := e.Positive.Offset + int32(i)
+		out = append(out, span{lo: exponentialLower(idx, e.Scale), hi: exponentialLower(idx+1, e.Scale), count: float64(c)})
+	}
+	for i, c := range e.Negative.BucketCounts {
+		if c == 0 {
+			continue
+		}
+		idx := e.Negative.Offset + int32(i)
+		out = append(out, span{lo: -exponentialLower(idx+1, e.Scale), hi: -exponentialLower(idx, e.Scale), count: float64(c)})
+	}
+	return out
+}
+
+// ToExplicit converts an exponential histogram into an explicit-bounds
+// data point using the given bounds.
+func (e *ExponentialHistogram) ToExplicit(bounds []float64) (*model.DataPoint, error) {
+	for i := 1; i < len(bounds); i++ {
+		if !(bounds[i] > bounds[i-1]) {
+			return nil, fmt.Errorf("%w: target bounds not strictly increasing at index %d", ErrInvalidLayout, i)
+		}
+	}
+	return &model.DataPoint{
+		Count:          e.Count,
+		Sum:            e.Sum,
+		ExplicitBounds: append([]float64(nil), bounds...),
+		BucketCounts:   roundCounts(redistribute(e.spans(), bounds)),
+	}, nil
+}
+
+// ToExponential converts an explicit-bounds data point into an exponential
+// histogram at the given scale. Counts of each explicit bucket are spread over
+// the exponential buckets it overlaps; point masses at exactly zero are
+// counted in ZeroCount.
+func ToExponential(dp *model.DataPoint, scale int32) (*ExponentialHistogram, error) {
+	if err := Validate(dp); err != nil {
+		return nil, err
+	}
+
+	out := &ExponentialHistogram{Scale: scale, Count: dp.Count, Sum: dp.Sum}
+	pos := map[int32]float64{}
+	neg := map[int32]float64{}
+	var zero float64
+
+	for _, s := range explicitSpans(dp.ExplicitBounds, dp.BucketCounts) {
+		if s.hi <= s.lo {
+			v := s.hi
+			if s.above && v != 0 {
+				v = math.Nextafter(v, math.Inf(1))
+			}
+			switch {
+			case v > 0:
+				pos[ExponentialIndex(v, scale)] += s.count
+			case v < 0:
+				neg[ExponentialIndex(-v, scale)] += s.count
+			default:
+				zero += s.count
+			}
+			continue
+		}
+
+		width := s.hi - s.lo
+		if s.hi > 0 {
+			lo := math.Max(s.lo, 0)
+			if err := spread(pos, lo, s.hi, s.count*(s.hi-lo)/width, scale); err != nil {
+				return nil, err
+			}
+		}
+		if s.lo < 0 {
+			hi := math.Min(s.hi, 0)
+			if err := spread(neg, -hi, -s.lo, s.count*(hi-s.lo)/width, scale); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var err error
+	if out.Positive, err = packBuckets(pos); err != nil {
+		return nil, err
+	}
+	if out.Negative, err = packBuckets(neg); err != nil {
+		return nil, err
+	}
+	out.ZeroCount = uint64(math.Round(zero))
+	out.fixTotal(dp)
+	return out, nil
+}
+
+// fixTotal gives the rounding remainder of the separately rounded zero,
+// positive and negative buckets to the largest bucket, so that the buckets
+// add up to the count of dp.
+func (e *ExponentialHistogram) fixTotal(dp *model.DataPoint) {
+	want := dp.Count
+	if want == 0 {
+		want = TotalCount(dp)
+	}
+	largest := &e.ZeroCount
+	total := e.ZeroCount
+	for _, side := range []*ExponentialBuckets{&e.Positive, &e.Negative} {
+		for i := range side.BucketCounts {
+			total += side.BucketCounts[i]
+			if side.BucketCounts[i] > *largest {
+				largest = &side.BucketCounts[i]
+			}
+		}
+	}
+	switch {
+	case total < want:
+		*largest += want - total
+	case total > want && total-want <= *largest:
+		*largest -= total - want
+	}
+}
+
+// spread distributes count uniformly over (lo, hi] (0 <= lo < hi) into
+// exponential buckets. A log scale cannot reach zero, so a span starting at
+// zero is truncated to (hi/1000, hi]; for a uniform distribution that moves
+// 0.1% of the count into the remaining buckets.
+func spread(into map[int32]float64, lo, hi, count float64, scale int32) error {
+	if lo == 0 {
+		lo = hi / 1000
+	}
+	first := ExponentialIndex(math.Nextafter(lo, math.Inf(1)), scale)
+	last := ExponentialIndex(hi, scale)
+	if last-first >= MaxExponentialBuckets {
+		return fmt.Errorf("histogram: conversion needs %d buckets at scale %d, limit is %d", last-first+1, scale, MaxExponentialBuckets)
+	}
+	width := hi - lo
+	for idx := first; idx <= last; idx++ {
+		bl := math.Max(exponentialLower(idx, scale), lo)
+		bh := math.Min(exponentialLower(idx+1, scale), hi)
+		if bh > bl {
+			into[idx] += count * (bh - bl) / width
+		}
+	}
+	return nil
+}
+
+// packBuckets turns sparse index counts into a contiguous ExponentialBuckets.
+func packBuckets(in map[int32]float64) (ExponentialBuckets, error) {
+	if len(in) == 0 {
+		return ExponentialBuckets{}, nil
+	}
+	minIdx, maxIdx := int32(math.MaxInt32), int32(math.MinInt32)
+	for idx := range in {
+		minIdx = min(minIdx, idx)
+		maxIdx = max(maxIdx, idx)
+	}
+	if int64(maxIdx)-int64(minIdx) >= MaxExponentialBuckets {
+		return ExponentialBuckets{}, fmt.Errorf("histogram: %d buckets exceeds limit of %d", int64(maxIdx)-int64(minIdx)+1, MaxExponentialBuckets)
+	}
+	dense := make([]float64, maxIdx-minIdx+1)
+	for idx, c := range in {
+		dense[idx-minIdx] = c
+	}
+	return ExponentialBuckets{Offset: minIdx, BucketCounts: roundCounts(dense)}, nil
+}
+
+// ExplicitBounds returns the edges of every populated bucket of e (including
+// the zero bucket) as sorted explicit bounds. Passing them to ToExplicit
+// converts the histogram without redistributing any counts.
+func (e *ExponentialHistogram) ExplicitBounds() []float64 {
+	seen := map[float64]struct{}{}
+	for _, s := range e.spans() {
+		seen[s.lo] = struct{}{}
+		seen[s.hi] = struct{}{}
+	}
+	bounds := make([]float64, 0, len(seen))
+	for b := range seen {
+		bounds = append(bounds, b)
+	}
+	sort.Float64s(bounds)
+	return bounds
+}
@@ -0,0 +1,290 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/histogram/histogram.go
+
+// Package histogram provides math helpers for histogram data points carried
+// in model.DataPoint (BucketCounts / ExplicitBounds). It supports quantile
+// estimation, merging, subtraction for rate calculation and conversion
+// between explicit and exponential bucket layouts.
+//
+// Explicit buckets follow the OTLP convention: with N bounds there are N+1
+// buckets, bucket i covers (bounds[i-1], bounds[i]], the first bucket covers
+// (-Inf, bounds[0]] and the last covers (bounds[N-1], +Inf).
+package histogram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+var (
+	// ErrNoBuckets is returned when a data point carries no bucket counts.
+	ErrNoBuckets = errors.New("histogram: data point has no buckets")
+
+	// ErrInvalidLayout is returned when BucketCounts and ExplicitBounds disagree
+	// or bounds are not strictly increasing.
+	ErrInvalidLayout = errors.New("histogram: invalid bucket layout")
+
+	// ErrBoundsMismatch is returned when two histograms must share bounds but do not.
+	ErrBoundsMismatch = errors.New("histogram: bucket bounds do not match")
+
+	// ErrCounterReset is returned by Subtract when any bucket decreased,
+	// which indicates the cumulative histogram was reset.
+	ErrCounterReset = errors.New("histogram: counter reset detected")
+
+	// ErrInvalidQuantile is returned for quantiles outside [0, 1].
+	ErrInvalidQuantile = errors.New("histogram: quantile must be between 0 and 1")
+)
+
+// Validate checks that the data point has a consistent explicit bucket layout.
+func Validate(dp *model.DataPoint) error {
+	if dp == nil || len(dp.BucketCounts) == 0 {
+		return ErrNoBuckets
+	}
+	if len(dp.BucketCounts) != len(dp.ExplicitBounds)+1 {
+		return fmt.Errorf("%w: %d counts for %d bounds", ErrInvalidLayout, len(dp.BucketCounts), len(dp.ExplicitBounds))
+	}
+	for i := 1; i < len(dp.ExplicitBounds); i++ {
+		if !(dp.ExplicitBounds[i] > dp.ExplicitBounds[i-1]) {
+			return fmt.Errorf("%w: bounds not strictly increasing at index %d", ErrInvalidLayout, i)
+		}
+	}
+	return nil
+}
+
+// TotalCount returns the sum of all bucket counts.
+func TotalCount(dp *model.DataPoint) uint64 {
+	var total uint64
+	for _, c := range dp.BucketCounts {
+		total += c
+	}
+	return total
+}
+
+// Mean returns Sum / Count for the data point, or NaN if it is empty.
+func Mean(dp *model.DataPoint) float64 {
+	count := dp.Count
+	if count == 0 {
+		count = TotalCount(dp)
+	}
+	if count == 0 {
+		return math.NaN()
+	}
+	return dp.Sum / float64(count)
+}
+
+// Observe records one value in an explicit-bounds data point, allocating
+// the bucket counts on first use and updating Count and Sum.
+func Observe(dp *model.DataPoint, v float64) {
+	if len(dp.BucketCounts) != len(dp.ExplicitBounds)+1 {
+		dp.BucketCounts = make([]uint64, len(dp.ExplicitBounds)+1)
+	}
+	dp.BucketCounts[bucketIndex(dp.ExplicitBounds, v)]++
+	dp.Count++
+	dp.Sum += v
+}
+
+// Quantile estimates the q-quantile (0 <= q <= 1) of the data point by linear
+// interpolation inside the bucket that contains the target rank.
+//
+// As in Prometheus, the lower edge of the first bucket is assumed to be 0 when
+// the first bound is positive, and a rank that lands in the overflow bucket
+// returns the highest finite bound.
+func Quantile(dp *model.DataPoint, q float64) (float64, error) {
+	if err := Validate(dp); err != nil {
+		return math.NaN(), err
+	}
+	if math.IsNaN(q) || q < 0 || q > 1 {
+		return math.NaN(), ErrInvalidQuantile
+	}
+
+	total := TotalCount(dp)
+	if total == 0 {
+		return math.NaN(), nil
+	}
+
+	bounds := dp.ExplicitBounds
+	if len(bounds) == 0 {
+		// Single (-Inf, +Inf) bucket: the best estimate is the mean.
+		return Mean(dp), nil
+	}
+
+	rank := q * float64(total)
+	var cumulative float64
+	for i, c := range dp.BucketCounts {
+		if c == 0 {
+			continue
+		}
+		prev := cumulative
+		cumulative += float64(c)
+		if cumulative < rank {
+			continue
+		}
+
+		switch {
+		case i == len(bounds):
+			return bounds[len(bounds)-1], nil
+		case i == 0:
+			if bounds[0] <= 0 {
+				return bounds[0], nil
+			}
+			return bounds[0] * (rank / cumulative), nil
+		default:
+			lo, hi := bounds[i-1], bounds[i]
+			return lo + (hi-lo)*((rank-prev)/float64(c)), nil
+		}
+	}
+
+	return bounds[len(bounds)-1], nil
+}
+
+// Quantiles estimates several quantiles at once and returns them as
+// model.QuantileValue entries, suitable for summary-style output.
+func Quantiles(dp *model.DataPoint, qs ...float64) ([]model.QuantileValue, error) {
+	out := make([]model.QuantileValue, 0, len(qs))
+	for _, q := range qs {
+		v, err := Quantile(dp, q)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, model.QuantileValue{Quantile: q, Value: v})
+	}
+	return out, nil
+}
+
+// SameBounds reports whether two bound slices are identical.
+func SameBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// span is a half-open value range (lo, hi] holding a fractional count.
+// Infinite edges are clamped before spans are built, so lo == hi is a point mass.
+// A point mass marked above lies just past hi: it is the overflow bucket,
+// whose values are all greater than the last bound.
+type span struct {
+	lo, hi float64
+	count  float64
+	above  bool
+}
+
+// explicitSpans turns an explicit histogram into finite spans using the same
+// edge assumptions as Quantile.
+func explicitSpans(bounds []float64, counts []uint64) []span {
+	spans := make([]span, 0, len(counts))
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+		var lo, hi float64
+		above := false
+		switch {
+		case len(bounds) == 0:
+			lo, hi = 0, 0
+		case i == 0:
+			hi = bounds[0]
+			lo = math.Min(0, hi)
+		case i == len(bounds):
+			lo, hi, above = bounds[i-1], bounds[i-1], true
+		default:
+			lo, hi = bounds[i-1], bounds[i]
+		}
+		spans = append(spans, span{lo: lo, hi: hi, count: float64(c), above: above})
+	}
+	return spans
+}
+
+// redistribute spreads span counts over explicit target bounds, assuming values
+// are uniformly distributed inside each span.
+func redistribute(spans []span, bounds []float64) []float64 {
+	out := make([]float64, len(bounds)+1)
+	for _, s := range spans {
+		if s.hi <= s.lo {
+			idx := bucketIndex(bounds, s.hi)
+			if s.above && idx < len(bounds) && bounds[idx] == s.hi {
+				idx++ // the bucket ending at hi holds no overflow values
+			}
+			out[idx] += s.count
+			continue
+		}
+		width := s.hi - s.lo
+		first := bucketIndex(bounds, math.Nextafter(s.lo, math.Inf(1)))
+		last := bucketIndex(bounds, s.hi)
+		for i := first; i <= last; i++ {
+			lo, hi := s.lo, s.hi
+			if i > 0 && bounds[i-1] > lo {
+				lo = bounds[i-1]
+			}
+			if i < len(bounds) && bounds[i] < hi {
+				hi = bounds[i]
+			}
+			if hi > lo {
+				out[i] += s.count * (hi - lo) / width
+			}
+		}
+	}
+	return out
+}
+
+// bucketIndex returns the explicit bucket that contains v.
+func bucketIndex(bounds []float64, v float64) int {
+	return sort.SearchFloat64s(bounds, v)
+}
+
+// roundCounts converts fractional counts to integers while preserving the
+// rounded total, using the largest-remainder method.
+func roundCounts(in []float64) []uint64 {
+	out := make([]uint64, len(in))
+	var total float64
+	type rem struct {
+		idx  int
+		frac float64
+	}
+	rems := make([]rem, 0, len(in))
+	var assigned uint64
+	for i, v := range in {
+		total += v
+		floor := math.Floor(v)
+		out[i] = uint64(floor)
+		assigned += out[i]
+		rems = append(rems, rem{idx: i, frac: v - floor})
+	}
+
+	missing := int64(math.Round(total)) - int64(assigned)
+	sort.SliceStable(rems, func(i, j int) bool { return rems[i].frac > rems[j].frac })
+	for i := 0; missing > 0 && i < len(rems); i++ {
+		out[rems[i].idx]++
+		missing--
+	}
+	return out
+}
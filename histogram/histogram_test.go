@@ -0,0 +1,269 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/histogram/histogram_test.go
+
+package histogram
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+func point(bounds []float64, counts ...uint64) *model.DataPoint {
+	dp := &model.DataPoint{ExplicitBounds: bounds, BucketCounts: counts}
+	dp.Count = TotalCount(dp)
+	return dp
+}
+
+func equalCounts(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQuantile(t *testing.T) {
+	// 2 values in (-Inf,1], 2 in (1,2], 4 in (2,4].
+	dp := point([]float64{1, 2, 4}, 2, 2, 4, 0)
+	tests := []struct {
+		q, want float64
+	}{
+		{0.25, 1}, // rank 2 ends the first bucket, assumed to start at 0
+		{0.5, 2},  // rank 4 ends (1,2]
+		{0.75, 3}, // rank 6 is halfway through (2,4]
+		{1, 4},
+		{0.125, 0.5},
+	}
+	for _, tt := range tests {
+		got, err := Quantile(dp, tt.q)
+		if err != nil {
+			t.Fatalf("Quantile(%v): %v", tt.q, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	if _, err := Quantile(dp, 1.5); err != ErrInvalidQuantile {
+		t.Errorf("Quantile(1.5) error = %v, want ErrInvalidQuantile", err)
+	}
+}
+
+func TestQuantileOverflow(t *testing.T) {
+	got, err := Quantile(point([]float64{1, 2}, 0, 0, 3), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 2 {
+		t.Errorf("overflow quantile = %v, want highest bound 2", got)
+	}
+}
+
+func TestQuantiles(t *testing.T) {
+	qs, err := Quantiles(point([]float64{1, 2, 4}, 2, 2, 4, 0), 0.5, 0.75)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.QuantileValue{{Quantile: 0.5, Value: 2}, {Quantile: 0.75, Value: 3}}
+	if len(qs) != len(want) {
+		t.Fatalf("got %d quantiles, want %d", len(qs), len(want))
+	}
+	for i := range want {
+		if qs[i] != want[i] {
+			t.Errorf("quantile %d = %+v, want %+v", i, qs[i], want[i])
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       *model.DataPoint
+		wantBounds []float64
+		wantCounts []uint64
+	}{
+		{
+			name:       "same bounds",
+			a:          point([]float64{1, 2}, 1, 2, 3),
+			b:          point([]float64{1, 2}, 4, 5, 6),
+			wantBounds: []float64{1, 2},
+			wantCounts: []uint64{5, 7, 9},
+		},
+		{
+			name:       "split uniformly",
+			a:          point([]float64{0, 10}, 0, 10, 0),
+			b:          point([]float64{0, 5, 10}, 0, 0, 0, 0),
+			wantBounds: []float64{0, 5, 10},
+			wantCounts: []uint64{0, 5, 5, 0},
+		},
+		{
+			// Values above 2 must not fall back into (1,2].
+			name:       "overflow",
+			a:          point([]float64{1, 2}, 0, 0, 5),
+			b:          point([]float64{1, 3}, 0, 0, 0),
+			wantBounds: []float64{1, 2, 3},
+			wantCounts: []uint64{0, 0, 5, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge(tt.a, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !SameBounds(got.ExplicitBounds, tt.wantBounds) {
+				t.Errorf("bounds = %v, want %v", got.ExplicitBounds, tt.wantBounds)
+			}
+			if !equalCounts(got.BucketCounts, tt.wantCounts) {
+				t.Errorf("counts = %v, want %v", got.BucketCounts, tt.wantCounts)
+			}
+			if got.Count != tt.a.Count+tt.b.Count {
+				t.Errorf("count = %d, want %d", got.Count, tt.a.Count+tt.b.Count)
+			}
+		})
+	}
+}
+
+func TestRebucket(t *testing.T) {
+	tests := []struct {
+		name   string
+		dp     *model.DataPoint
+		bounds []float64
+		want   []uint64
+	}{
+		{"finer", point([]float64{0, 4}, 0, 8, 0), []float64{0, 1, 2, 4}, []uint64{0, 2, 2, 4, 0}},
+		{"coarser", point([]float64{1, 2, 3}, 1, 2, 3, 4), []float64{2}, []uint64{3, 7}},
+		{"overflow into wider bounds", point([]float64{1}, 0, 3), []float64{1, 5}, []uint64{0, 3, 0}},
+		{"overflow stays overflow", point([]float64{1}, 0, 3), []float64{0.5, 1}, []uint64{0, 0, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Rebucket(tt.dp, tt.bounds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalCounts(got.BucketCounts, tt.want) {
+				t.Errorf("counts = %v, want %v", got.BucketCounts, tt.want)
+			}
+		})
+	}
+
+	if _, err := Rebucket(point([]float64{1}, 0, 1), []float64{2, 1}); err == nil {
+		t.Error("Rebucket accepted decreasing bounds")
+	}
+}
+
+func TestExponentialIndex(t *testing.T) {
+	tests := []struct {
+		v     float64
+		scale int32
+		want  int32
+	}{
+		{2, 0, 0}, // (1,2]
+		{3, 0, 1}, // (2,4]
+		{4, 0, 1},
+		{1, 0, -1},
+		{1.2, 1, 0}, // (1,sqrt2]
+		{1.5, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := ExponentialIndex(tt.v, tt.scale); got != tt.want {
+			t.Errorf("ExponentialIndex(%v, %d) = %d, want %d", tt.v, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestExponentialToExplicit(t *testing.T) {
+	// Scale 0: 4 values in (1,2], 2 in (2,4] and 1 exact zero.
+	e := &ExponentialHistogram{
+		Scale:     0,
+		ZeroCount: 1,
+		Positive:  ExponentialBuckets{Offset: 0, BucketCounts: []uint64{4, 2}},
+		Count:     7,
+	}
+	tests := []struct {
+		name   string
+		bounds []float64
+		want   []uint64
+	}{
+		{"aligned", []float64{0, 2, 4}, []uint64{1, 4, 2, 0}},
+		{"split", []float64{0, 1.5, 3}, []uint64{1, 2, 3, 1}},
+		{"own bounds", e.ExplicitBounds(), []uint64{1, 0, 4, 2, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.ToExplicit(tt.bounds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalCounts(got.BucketCounts, tt.want) {
+				t.Errorf("counts = %v, want %v", got.BucketCounts, tt.want)
+			}
+			if got.Count != e.Count {
+				t.Errorf("count = %d, want %d", got.Count, e.Count)
+			}
+		})
+	}
+}
+
+func TestToExponentialOverflow(t *testing.T) {
+	// Three values above 2 belong in (2,4], not in (1,2].
+	e, err := ToExponential(point([]float64{2}, 0, 3), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Positive.Offset != 1 || !equalCounts(e.Positive.BucketCounts, []uint64{3}) {
+		t.Errorf("positive = %+v, want offset 1 counts [3]", e.Positive)
+	}
+}
+
+func TestToExponentialTotal(t *testing.T) {
+	// (-1,1] splits 1.5/1.5 between the negative and positive sides; each
+	// side rounds to 2 on its own.
+	tests := []*model.DataPoint{
+		point([]float64{-1, 1}, 0, 3, 0),
+		point([]float64{-2, 0, 3}, 1, 3, 5, 2),
+	}
+	for _, dp := range tests {
+		e, err := ToExponential(dp, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := e.ZeroCount
+		for _, c := range e.Positive.BucketCounts {
+			total += c
+		}
+		for _, c := range e.Negative.BucketCounts {
+			total += c
+		}
+		if total != dp.Count {
+			t.Errorf("bounds %v: buckets add up to %d, want Count %d", dp.ExplicitBounds, total, dp.Count)
+		}
+	}
+}
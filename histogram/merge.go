@@ -0,0 +1,191 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/histogram/merge.go
+
+package histogram
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Rebucket redistributes the counts of dp onto a new set of explicit bounds.
+// Counts are split proportionally across target buckets that overlap a source
+// bucket, assuming values are uniformly distributed within each bucket.
+// Count and Sum are carried over unchanged.
+func Rebucket(dp *model.DataPoint, bounds []float64) (*model.DataPoint, error) {
+	if err := Validate(dp); err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(bounds); i++ {
+		if !(bounds[i] > bounds[i-1]) {
+			return nil, fmt.Errorf("%w: target bounds not strictly increasing at index %d", ErrInvalidLayout, i)
+		}
+	}
+
+	out := copyPoint(dp)
+	out.ExplicitBounds = append([]float64(nil), bounds...)
+	if SameBounds(dp.ExplicitBounds, bounds) {
+		return out, nil
+	}
+	out.BucketCounts = roundCounts(redistribute(explicitSpans(dp.ExplicitBounds, dp.BucketCounts), bounds))
+	return out, nil
+}
+
+// Merge combines several histogram data points into one. When all inputs share
+// the same bounds their buckets are added directly; otherwise every input is
+// re-bucketed onto the sorted union of all bounds before adding.
+//
+// Attributes and timestamps are taken from the first point, with
+// StartTimestamp widened to the earliest and Timestamp to the latest input.
+func Merge(points ...*model.DataPoint) (*model.DataPoint, error) {
+	if len(points) == 0 {
+		return nil, ErrNoBuckets
+	}
+	for _, p := range points {
+		if err := Validate(p); err != nil {
+			return nil, err
+		}
+	}
+
+	bounds := points[0].ExplicitBounds
+	for _, p := range points[1:] {
+		if !SameBounds(bounds, p.ExplicitBounds) {
+			bounds = unionBounds(points)
+			break
+		}
+	}
+
+	out := copyPoint(points[0])
+	out.ExplicitBounds = append([]float64(nil), bounds...)
+	out.BucketCounts = make([]uint64, len(bounds)+1)
+	out.Count, out.Sum = 0, 0
+	out.Exemplars = nil
+
+	for _, p := range points {
+		src := p
+		if !SameBounds(bounds, p.ExplicitBounds) {
+			var err error
+			if src, err = Rebucket(p, bounds); err != nil {
+				return nil, err
+			}
+		}
+		for i, c := range src.BucketCounts {
+			out.BucketCounts[i] += c
+		}
+		out.Count += p.Count
+		out.Sum += p.Sum
+		out.Exemplars = append(out.Exemplars, p.Exemplars...)
+
+		if !p.StartTimestamp.IsZero() && (out.StartTimestamp.IsZero() || p.StartTimestamp.Before(out.StartTimestamp)) {
+			out.StartTimestamp = p.StartTimestamp
+		}
+		if p.Timestamp.After(out.Timestamp) {
+			out.Timestamp = p.Timestamp
+		}
+	}
+	return out, nil
+}
+
+// Subtract returns cur - prev for two cumulative histograms with the same
+// bounds, producing the delta histogram for the interval between them. It
+// returns ErrCounterReset if any bucket or the count went backwards.
+func Subtract(cur, prev *model.DataPoint) (*model.DataPoint, error) {
+	if err := Validate(cur); err != nil {
+		return nil, err
+	}
+	if err := Validate(prev); err != nil {
+		return nil, err
+	}
+	if !SameBounds(cur.ExplicitBounds, prev.ExplicitBounds) {
+		return nil, ErrBoundsMismatch
+	}
+	if cur.Count < prev.Count {
+		return nil, ErrCounterReset
+	}
+
+	out := copyPoint(cur)
+	for i := range cur.BucketCounts {
+		if cur.BucketCounts[i] < prev.BucketCounts[i] {
+			return nil, fmt.Errorf("%w: bucket %d", ErrCounterReset, i)
+		}
+		out.BucketCounts[i] = cur.BucketCounts[i] - prev.BucketCounts[i]
+	}
+	out.Count = cur.Count - prev.Count
+	out.Sum = cur.Sum - prev.Sum
+	out.StartTimestamp = prev.Timestamp
+	return out, nil
+}
+
+// Rate converts the delta between two cumulative histograms into per-second
+// bucket rates, keyed by bucket index. The interval is taken from the point
+// timestamps.
+func Rate(cur, prev *model.DataPoint) ([]float64, error) {
+	delta, err := Subtract(cur, prev)
+	if err != nil {
+		return nil, err
+	}
+	secs := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+	if secs <= 0 {
+		return nil, fmt.Errorf("histogram: non-positive interval %.3fs", secs)
+	}
+	rates := make([]float64, len(delta.BucketCounts))
+	for i, c := range delta.BucketCounts {
+		rates[i] = float64(c) / secs
+	}
+	return rates, nil
+}
+
+// unionBounds returns the sorted, de-duplicated union of all point bounds.
+func unionBounds(points []*model.DataPoint) []float64 {
+	seen := make(map[float64]struct{})
+	var out []float64
+	for _, p := range points {
+		for _, b := range p.ExplicitBounds {
+			if _, ok := seen[b]; ok {
+				continue
+			}
+			seen[b] = struct{}{}
+			out = append(out, b)
+		}
+	}
+	sort.Float64s(out)
+	return out
+}
+
+// copyPoint returns a copy of dp with its slices and attributes cloned.
+func copyPoint(dp *model.DataPoint) *model.DataPoint {
+	out := *dp
+	out.BucketCounts = append([]uint64(nil), dp.BucketCounts...)
+	out.ExplicitBounds = append([]float64(nil), dp.ExplicitBounds...)
+	out.QuantileValues = append([]model.QuantileValue(nil), dp.QuantileValues...)
+	out.Exemplars = append([]model.Exemplar(nil), dp.Exemplars...)
+	if dp.Attributes != nil {
+		out.Attributes = make(map[string]string, len(dp.Attributes))
+		for k, v := range dp.Attributes {
+			out.Attributes[k] = v
+		}
+	}
+	return &out
+}
@@ -0,0 +1,261 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/logpipeline/extract.go
+
+package logpipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// jsonProcessor decodes a JSON object. Nested objects are flattened with
+// dotted keys when the target is fields and kept as maps for attributes.
+type jsonProcessor struct {
+	cfg model.LogProcessorConfig
+}
+
+func (p *jsonProcessor) process(e *model.LogEntry) error {
+	in, ok := input(e, p.cfg.Field)
+	in = strings.TrimSpace(in)
+	if !ok || !strings.HasPrefix(in, "{") {
+		return ErrNoMatch
+	}
+	dec := json.NewDecoder(strings.NewReader(in))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return ErrNoMatch
+	}
+	normalizeNumbers(obj)
+
+	if p.cfg.Target == TargetAttributes {
+		store(e, p.cfg, obj)
+		return nil
+	}
+	flat := map[string]interface{}{}
+	flatten("", obj, flat)
+	store(e, p.cfg, flat)
+	return nil
+}
+
+// normalizeNumbers replaces json.Number with int64 or float64.
+func normalizeNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, child := range t {
+			t[k] = normalizeNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = normalizeNumbers(child)
+		}
+	}
+	return v
+}
+
+func flatten(prefix string, obj map[string]interface{}, out map[string]interface{}) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch t := v.(type) {
+		case map[string]interface{}:
+			flatten(key, t, out)
+		case []interface{}:
+			b, _ := json.Marshal(t)
+			out[key] = string(b)
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = t
+		}
+	}
+}
+
+// logfmtProcessor decodes key=value pairs. Bare keys are set to "true".
+type logfmtProcessor struct {
+	cfg model.LogProcessorConfig
+}
+
+func (p *logfmtProcessor) process(e *model.LogEntry) error {
+	in, ok := input(e, p.cfg.Field)
+	if !ok {
+		return ErrNoMatch
+	}
+	values, ok := parseLogfmt(in)
+	if !ok {
+		return ErrNoMatch
+	}
+	store(e, p.cfg, values)
+	return nil
+}
+
+// parseLogfmt parses logfmt. It fails unless at least one key=value pair is
+// present, so plain text is not mistaken for a list of bare keys.
+func parseLogfmt(s string) (map[string]interface{}, bool) {
+	out := map[string]interface{}{}
+	pairs := 0
+	for i := 0; i < len(s); {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' && s[i] != '"' {
+			i++
+		}
+		key := s[start:i]
+		if key == "" {
+			if i < len(s) {
+				return nil, false
+			}
+			break
+		}
+		if i >= len(s) || s[i] != '=' {
+			out[key] = "true"
+			continue
+		}
+		i++ // '='
+		pairs++
+		if i < len(s) && s[i] == '"' {
+			var b strings.Builder
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(s[i])
+					}
+				} else {
+					b.WriteByte(s[i])
+				}
+				i++
+			}
+			if i >= len(s) {
+				return nil, false
+			}
+			i++ // closing quote
+			out[key] = b.String()
+			continue
+		}
+		vstart := i
+		for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+			i++
+		}
+		out[key] = s[vstart:i]
+	}
+	return out, pairs > 0
+}
+
+// regexProcessor extracts named groups from the first matching pattern.
+type regexProcessor struct {
+	cfg      model.LogProcessorConfig
+	patterns []*compiledPattern
+}
+
+// compiledPattern maps capture group indexes to output keys and optional
+// type conversions ("int", "float").
+type compiledPattern struct {
+	re    *regexp.Regexp
+	names []string
+	types []string
+}
+
+func newRegexProcessor(cfg model.LogProcessorConfig) (processor, error) {
+	if len(cfg.Patterns) == 0 {
+		return nil, fmt.Errorf("regex processor needs at least one pattern")
+	}
+	p := &regexProcessor{cfg: cfg}
+	for _, pat := range cfg.Patterns {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", pat, err)
+		}
+		p.patterns = append(p.patterns, &compiledPattern{
+			re:    re,
+			names: re.SubexpNames(),
+			types: make([]string, len(re.SubexpNames())),
+		})
+	}
+	return p, nil
+}
+
+func (p *regexProcessor) process(e *model.LogEntry) error {
+	return extractFirst(e, p.cfg, p.patterns)
+}
+
+// extractFirst stores the groups of the first pattern that matches. Groups
+// that did not participate in the match are skipped, so alternations can
+// reuse a name.
+func extractFirst(e *model.LogEntry, cfg model.LogProcessorConfig, patterns []*compiledPattern) error {
+	in, ok := input(e, cfg.Field)
+	if !ok {
+		return ErrNoMatch
+	}
+	for _, cp := range patterns {
+		idx := cp.re.FindStringSubmatchIndex(in)
+		if idx == nil {
+			continue
+		}
+		values := map[string]interface{}{}
+		for g := 1; g < len(cp.names); g++ {
+			if cp.names[g] == "" || idx[2*g] < 0 {
+				continue
+			}
+			values[cp.names[g]] = convert(in[idx[2*g]:idx[2*g+1]], cp.types[g])
+		}
+		store(e, cfg, values)
+		return nil
+	}
+	return ErrNoMatch
+}
+
+func convert(s, typ string) interface{} {
+	switch typ {
+	case "int":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
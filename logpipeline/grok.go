@@ -0,0 +1,204 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/logpipeline/grok.go
+
+package logpipeline
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// GrokPatterns is the built-in grok library: the common Logstash base
+// patterns (rewritten for RE2) plus nginx, apache and sshd formats.
+// Output field names follow OpenTelemetry semantic conventions where one
+// exists.
+var GrokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"INT":               `[+-]?[0-9]+`,
+	"BASE10NUM":         `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"NUMBER":            `%{BASE10NUM}`,
+	"POSINT":            `\b[1-9][0-9]*\b`,
+	"NONNEGINT":         `\b[0-9]+\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{1,4}:){1,7}(?::|(?::?[0-9A-Fa-f]{1,4}){1,7})|::(?:[0-9A-Fa-f]{1,4}:?){0,7}`,
+	"IP":                `(?:%{IPV4}|%{IPV6})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"URIPROTO":          `[A-Za-z][A-Za-z0-9+\-.]*`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":               `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?(?:%{IPORHOST}(?::%{POSINT})?)?(?:%{URIPATHPARAM})?`,
+	"MONTH":             `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|Jun(?:e)?|Jul(?:y)?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0[1-9]|[12][0-9]|3[01]|[1-9])`,
+	"DAY":               `(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"PROG":              `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"LOGLEVEL":          `(?:[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo|INFO|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|[Ee]merg(?:ency)?|EMERG(?:ENCY)?)`,
+
+	// nginx
+	"NGINX_ACCESS":     `%{IPORHOST:client.address} - %{NOTSPACE:user.name} \[%{HTTPDATE:timestamp}\] "%{WORD:http.request.method} %{NOTSPACE:url.path} HTTP/%{NUMBER:network.protocol.version}" %{INT:http.response.status_code:int} (?:%{INT:http.response.body.size:int}|-)(?: "%{DATA:http.request.header.referer}" "%{DATA:user_agent.original}")?`,
+	"NGINX_ERROR_TIME": `\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`,
+	"NGINX_ERROR":      `%{NGINX_ERROR_TIME:timestamp} \[%{LOGLEVEL:level}\] %{POSINT:process.pid:int}#%{NONNEGINT:thread.id:int}: (?:\*%{NONNEGINT:nginx.connection_id:int} )?%{GREEDYDATA:message}`,
+
+	// apache httpd
+	"APACHE_COMMON":     `%{IPORHOST:client.address} %{NOTSPACE:apache.ident} %{NOTSPACE:user.name} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:http.request.method} %{NOTSPACE:url.path}(?: HTTP/%{NUMBER:network.protocol.version})?|%{DATA:apache.raw_request})" %{INT:http.response.status_code:int} (?:%{INT:http.response.body.size:int}|-)`,
+	"APACHE_COMBINED":   `%{APACHE_COMMON} "%{DATA:http.request.header.referer}" "%{DATA:user_agent.original}"`,
+	"APACHE_ERROR_TIME": `%{DAY} %{MONTH} %{MONTHDAY} %{TIME} %{YEAR}`,
+	"APACHE_ERROR":      `\[%{APACHE_ERROR_TIME:timestamp}\] \[(?:%{WORD:apache.module})?:?%{LOGLEVEL:level}\] \[pid %{POSINT:process.pid:int}(?::tid %{NONNEGINT:thread.id:int})?\](?: \[client %{IPORHOST:client.address}(?::%{POSINT:client.port:int})?\])? %{GREEDYDATA:message}`,
+
+	// OpenSSH sshd
+	"SSHD_ACCEPTED":     `Accepted %{WORD:ssh.auth_method} for %{USERNAME:user.name} from %{IP:source.address} port %{POSINT:source.port:int}(?: %{WORD:ssh.protocol})?(?:: %{GREEDYDATA:ssh.key})?`,
+	"SSHD_FAILED":       `Failed %{WORD:ssh.auth_method} for (?:invalid user )?%{USERNAME:user.name} from %{IP:source.address} port %{POSINT:source.port:int}(?: %{WORD:ssh.protocol})?`,
+	"SSHD_INVALID_USER": `Invalid user %{DATA:user.name} from %{IP:source.address}(?: port %{POSINT:source.port:int})?`,
+	"SSHD_DISCONNECT":   `(?:Disconnected from|Connection closed by|Received disconnect from) (?:(?:invalid |authenticating )?user %{USERNAME:user.name} )?%{IP:source.address} port %{POSINT:source.port:int}%{GREEDYDATA:ssh.reason}`,
+	"SSHD":              `%{SSHD_ACCEPTED}|%{SSHD_FAILED}|%{SSHD_INVALID_USER}|%{SSHD_DISCONNECT}`,
+}
+
+// grokRef matches %{NAME}, %{NAME:field} and %{NAME:field:type}.
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::([\w.@\-\[\]]+))?(?::(int|float))?\}`)
+
+// maxGrokDepth bounds pattern expansion so recursive definitions fail
+// instead of looping.
+const maxGrokDepth = 16
+
+type grokProcessor struct {
+	cfg      model.LogProcessorConfig
+	patterns []*compiledPattern
+}
+
+func newGrokProcessor(cfg model.LogProcessorConfig) (processor, error) {
+	if len(cfg.Patterns) == 0 {
+		return nil, fmt.Errorf("grok processor needs at least one pattern")
+	}
+	p := &grokProcessor{cfg: cfg}
+	for _, pat := range cfg.Patterns {
+		cp, err := compileGrok(pat, cfg.Definitions)
+		if err != nil {
+			return nil, err
+		}
+		p.patterns = append(p.patterns, cp)
+	}
+	return p, nil
+}
+
+func (p *grokProcessor) process(e *model.LogEntry) error {
+	return extractFirst(e, p.cfg, p.patterns)
+}
+
+// ExpandGrok expands a grok expression into a Go regular expression, using
+// defs before GrokPatterns. Named references become capture groups.
+func ExpandGrok(pattern string, defs map[string]string) (string, error) {
+	g := newGrokCompiler(defs)
+	return g.expand(pattern, 0)
+}
+
+func compileGrok(pattern string, defs map[string]string) (*compiledPattern, error) {
+	g := newGrokCompiler(defs)
+	expr, err := g.expand(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("grok %q: %w", pattern, err)
+	}
+	// Grok captures get generated group names; map them back to fields.
+	// Plain (?P<name>...) groups keep their own names.
+	names := append([]string(nil), re.SubexpNames()...)
+	types := make([]string, len(names))
+	for i, n := range names {
+		if field, ok := g.fields[n]; ok {
+			names[i] = field
+			types[i] = g.types[n]
+		}
+	}
+	return &compiledPattern{re: re, names: names, types: types}, nil
+}
+
+type grokCompiler struct {
+	defs   map[string]string
+	n      int
+	fields map[string]string // generated group name -> field
+	types  map[string]string // generated group name -> int/float
+}
+
+func newGrokCompiler(defs map[string]string) *grokCompiler {
+	return &grokCompiler{defs: defs, fields: map[string]string{}, types: map[string]string{}}
+}
+
+func (g *grokCompiler) expand(pattern string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok: pattern nesting deeper than %d (recursive definition?)", maxGrokDepth)
+	}
+	var firstErr error
+	out := grokRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if firstErr != nil {
+			return ""
+		}
+		m := grokRef.FindStringSubmatch(ref)
+		name, field, typ := m[1], m[2], m[3]
+		def, ok := g.defs[name]
+		if !ok {
+			def, ok = GrokPatterns[name]
+		}
+		if !ok {
+			firstErr = fmt.Errorf("grok: unknown pattern %%{%s}", name)
+			return ""
+		}
+		inner, err := g.expand(def, depth+1)
+		if err != nil {
+			firstErr = err
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		g.n++
+		group := fmt.Sprintf("grok%d", g.n)
+		g.fields[group] = field
+		g.types[group] = typ
+		return "(?P<" + group + ">" + inner + ")"
+	})
+	return out, firstErr
+}
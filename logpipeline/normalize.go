@@ -0,0 +1,170 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/logpipeline/normalize.go
+
+package logpipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/severity"
+)
+
+// severityKeys are read, in order, when no input field is configured.
+var severityKeys = []string{"level", "severity", "lvl", "loglevel", "log.level", "priority"}
+
+// severityProcessor normalizes a raw level into Level, SeverityText and
+// SeverityNumber.
+type severityProcessor struct {
+	cfg model.LogProcessorConfig
+}
+
+func (p *severityProcessor) process(e *model.LogEntry) error {
+	if e.Level != "" && !p.cfg.Overwrite {
+		return nil
+	}
+	raw, ok := "", false
+	if p.cfg.Field != "" {
+		raw, ok = input(e, p.cfg.Field)
+	} else {
+		for _, k := range severityKeys {
+			if v, found := e.Fields[k]; found && v != "" {
+				raw, ok = v, true
+				break
+			}
+		}
+		if !ok && e.SeverityText != "" {
+			raw, ok = e.SeverityText, true
+		}
+	}
+	if !ok {
+		return ErrNoMatch
+	}
+	if mapped, found := p.cfg.Mapping[strings.ToLower(raw)]; found {
+		raw = mapped
+	}
+	l, ok := parseLevel(raw)
+	if !ok {
+		return ErrNoMatch
+	}
+	severity.Apply(e, l)
+	return nil
+}
+
+// parseLevel accepts level names and syslog severity codes (0-7).
+func parseLevel(raw string) (severity.Level, bool) {
+	if n, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil {
+		l := severity.FromSyslog(n)
+		return l, l.Valid()
+	}
+	return severity.Parse(raw)
+}
+
+// Categories are the standardized log categories documented in model/log.go.
+var Categories = []string{
+	"system", "auth", "security", "network", "app", "container",
+	"metric", "gosight", "scheduler", "config", "audit", "alert",
+}
+
+// defaultCategories assigns a category by application or source name when
+// no rule matches.
+var defaultCategories = map[string]string{
+	"kernel": "system", "systemd": "system", "systemd-logind": "system", "init": "system", "udev": "system",
+	"sshd": "auth", "sudo": "auth", "su": "auth", "login": "auth", "pam": "auth", "polkitd": "auth",
+	"iptables": "security", "ufw": "security", "firewalld": "security", "auditd": "audit", "fail2ban": "security",
+	"sonicwall": "security", "fortinet": "security",
+	"dhclient": "network", "dhcpd": "network", "networkmanager": "network", "named": "network", "dnsmasq": "network",
+	"docker": "container", "dockerd": "container", "containerd": "container", "podman": "container",
+	"cron": "scheduler", "crond": "scheduler", "anacron": "scheduler",
+	"nginx": "app", "apache": "app", "httpd": "app", "apache2": "app", "postgres": "app", "mysqld": "app",
+	"gosight": "gosight", "gosight-agent": "gosight", "gosight-server": "gosight",
+}
+
+type categoryRule struct {
+	category string
+	field    string
+	re       *regexp.Regexp
+}
+
+// categoryProcessor assigns LogEntry.Category from rules, falling back to
+// the application or source name.
+type categoryProcessor struct {
+	cfg   model.LogProcessorConfig
+	rules []categoryRule
+}
+
+func newCategoryProcessor(cfg model.LogProcessorConfig) (processor, error) {
+	p := &categoryProcessor{cfg: cfg}
+	for _, r := range cfg.Rules {
+		if !validCategory(r.Category) {
+			return nil, fmt.Errorf("unknown category %q", r.Category)
+		}
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("category %s: invalid regex %q: %w", r.Category, r.Regex, err)
+		}
+		p.rules = append(p.rules, categoryRule{category: r.Category, field: r.Field, re: re})
+	}
+	for raw, cat := range cfg.Mapping {
+		if !validCategory(cat) {
+			return nil, fmt.Errorf("mapping %s: unknown category %q", raw, cat)
+		}
+	}
+	return p, nil
+}
+
+func (p *categoryProcessor) process(e *model.LogEntry) error {
+	if e.Category != "" && !p.cfg.Overwrite {
+		return nil
+	}
+	for _, r := range p.rules {
+		if in, ok := input(e, r.field); ok && r.re.MatchString(in) {
+			e.Category = r.category
+			return nil
+		}
+	}
+	for _, name := range []string{AppName(e), e.Source} {
+		name = strings.ToLower(name)
+		if cat, ok := p.cfg.Mapping[name]; ok {
+			e.Category = cat
+			return nil
+		}
+		if cat, ok := defaultCategories[name]; ok {
+			e.Category = cat
+			return nil
+		}
+	}
+	return ErrNoMatch
+}
+
+func validCategory(c string) bool {
+	for _, known := range Categories {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
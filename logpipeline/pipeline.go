@@ -0,0 +1,265 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/logpipeline/pipeline.go
+
+// Package logpipeline parses and normalizes log entries with configurable
+// pipelines of processors: JSON and logfmt decoding, regex and grok
+// extraction, timestamp parsing, severity normalization and category
+// assignment. Pipelines are selected per entry by Source and AppName.
+package logpipeline
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Processor types.
+const (
+	TypeJSON      = "json"
+	TypeLogfmt    = "logfmt"
+	TypeRegex     = "regex"
+	TypeGrok      = "grok"
+	TypeTimestamp = "timestamp"
+	TypeSeverity  = "severity"
+	TypeCategory  = "category"
+)
+
+// Targets for extracted values.
+const (
+	TargetFields     = "fields"
+	TargetAttributes = "attributes"
+)
+
+// ErrNoMatch is returned by extracting processors whose input does not parse.
+// It is not fatal: the pipeline moves on to the next processor.
+var ErrNoMatch = errors.New("logpipeline: no match")
+
+// processor is one compiled pipeline step.
+type processor interface {
+	process(e *model.LogEntry) error
+}
+
+// Pipeline is a compiled model.LogPipeline.
+type Pipeline struct {
+	Name       string
+	sources    map[string]bool
+	appNames   map[string]bool
+	processors []processor
+}
+
+// Engine holds compiled pipelines. It is immutable after construction and
+// safe for concurrent use.
+type Engine struct {
+	pipelines []*Pipeline
+}
+
+// NewEngine compiles every pipeline in the set.
+func NewEngine(set model.LogPipelineSet) (*Engine, error) {
+	e := &Engine{}
+	for i, cfg := range set.Pipelines {
+		p, err := Compile(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("logpipeline: pipeline %d (%s): %w", i, cfg.Name, err)
+		}
+		e.pipelines = append(e.pipelines, p)
+	}
+	return e, nil
+}
+
+// Compile compiles a single pipeline.
+func Compile(cfg model.LogPipeline) (*Pipeline, error) {
+	p := &Pipeline{
+		Name:     cfg.Name,
+		sources:  lowerSet(cfg.Sources),
+		appNames: lowerSet(cfg.AppNames),
+	}
+	for i, pc := range cfg.Processors {
+		proc, err := compileProcessor(pc)
+		if err != nil {
+			return nil, fmt.Errorf("processor %d (%s): %w", i, pc.Type, err)
+		}
+		p.processors = append(p.processors, proc)
+	}
+	return p, nil
+}
+
+func compileProcessor(cfg model.LogProcessorConfig) (processor, error) {
+	switch cfg.Target {
+	case "", TargetFields, TargetAttributes:
+	default:
+		return nil, fmt.Errorf("unknown target %q", cfg.Target)
+	}
+	switch strings.ToLower(cfg.Type) {
+	case TypeJSON:
+		return &jsonProcessor{cfg: cfg}, nil
+	case TypeLogfmt:
+		return &logfmtProcessor{cfg: cfg}, nil
+	case TypeRegex:
+		return newRegexProcessor(cfg)
+	case TypeGrok:
+		return newGrokProcessor(cfg)
+	case TypeTimestamp:
+		return newTimestampProcessor(cfg)
+	case TypeSeverity:
+		return &severityProcessor{cfg: cfg}, nil
+	case TypeCategory:
+		return newCategoryProcessor(cfg)
+	}
+	return nil, fmt.Errorf("unknown processor type %q", cfg.Type)
+}
+
+// Matches reports whether the pipeline selects the entry.
+func (p *Pipeline) Matches(e *model.LogEntry) bool {
+	if len(p.sources) > 0 && !p.sources[strings.ToLower(e.Source)] {
+		return false
+	}
+	if len(p.appNames) > 0 && !p.appNames[strings.ToLower(AppName(e))] {
+		return false
+	}
+	return true
+}
+
+// Process runs every processor over the entry. Processors whose input does
+// not match are skipped; other errors stop the pipeline and are returned.
+func (p *Pipeline) Process(e *model.LogEntry) error {
+	for _, proc := range p.processors {
+		if err := proc.process(e); err != nil && !errors.Is(err, ErrNoMatch) {
+			return fmt.Errorf("logpipeline: %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Process runs the first matching pipeline over the entry and returns its
+// name, or "" if none matched.
+func (eng *Engine) Process(e *model.LogEntry) (string, error) {
+	for _, p := range eng.pipelines {
+		if p.Matches(e) {
+			return p.Name, p.Process(e)
+		}
+	}
+	return "", nil
+}
+
+// ProcessPayload processes every entry in the payload. Entries without Meta
+// inherit the payload Meta for AppName selection. The first error is
+// returned after all entries have been processed.
+func (eng *Engine) ProcessPayload(payload *model.LogPayload) error {
+	var firstErr error
+	for i := range payload.Logs {
+		e := &payload.Logs[i]
+		if e.Meta == nil {
+			e.Meta = payload.Meta
+		}
+		if _, err := eng.Process(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// AppName returns the application an entry came from: Meta.AppName, then
+// the "app_name" field set by the syslog parser.
+func AppName(e *model.LogEntry) string {
+	if e.Meta != nil && e.Meta.AppName != "" {
+		return e.Meta.AppName
+	}
+	return e.Fields["app_name"]
+}
+
+// input returns the text a processor reads: "message" (the default, falling
+// back to Body), "body", or a field or attribute of that name.
+func input(e *model.LogEntry, field string) (string, bool) {
+	switch field {
+	case "", "message":
+		if e.Message != "" {
+			return e.Message, true
+		}
+		return e.Body, e.Body != ""
+	case "body":
+		return e.Body, e.Body != ""
+	}
+	if v, ok := e.Fields[field]; ok {
+		return v, true
+	}
+	if v, ok := e.Attributes[field]; ok && v != nil {
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// store writes extracted values to the configured target. A "message" or
+// "msg" value replaces the entry Message instead.
+func store(e *model.LogEntry, cfg model.LogProcessorConfig, values map[string]interface{}) {
+	for k, v := range values {
+		if k == "message" || k == "msg" {
+			if s, ok := v.(string); ok {
+				e.Message = s
+				continue
+			}
+		}
+		key := cfg.Prefix + k
+		if cfg.Target == TargetAttributes {
+			if e.Attributes == nil {
+				e.Attributes = map[string]interface{}{}
+			}
+			if _, exists := e.Attributes[key]; exists && !cfg.Overwrite {
+				continue
+			}
+			e.Attributes[key] = v
+			continue
+		}
+		if e.Fields == nil {
+			e.Fields = map[string]string{}
+		}
+		if _, exists := e.Fields[key]; exists && !cfg.Overwrite {
+			continue
+		}
+		e.Fields[key] = stringify(v)
+	}
+}
+
+// stringify renders a field value, keeping floats out of exponent notation.
+func stringify(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func lowerSet(vals []string) map[string]bool {
+	if len(vals) == 0 {
+		return nil
+	}
+	out := make(map[string]bool, len(vals))
+	for _, v := range vals {
+		out[strings.ToLower(v)] = true
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/logpipeline/timestamp.go
+
+package logpipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// namedLayouts are the shorthand names accepted in LogProcessorConfig.Layouts.
+var namedLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"httpdate":    "02/Jan/2006:15:04:05 -0700",
+	"syslog":      time.Stamp,
+	"nginx_error": "2006/01/02 15:04:05",
+	"apache":      "Mon Jan 02 15:04:05.000000 2006",
+}
+
+// defaultLayouts are tried when no layouts are configured.
+var defaultLayouts = []string{
+	"rfc3339nano", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999",
+	"httpdate", "nginx_error", "apache", "Mon Jan 02 15:04:05 2006", "syslog", "unix",
+}
+
+// timestampKeys are read, in order, when no input field is configured.
+var timestampKeys = []string{"timestamp", "time", "ts", "@timestamp", "datetime", "date"}
+
+// timestampProcessor parses a field into LogEntry.Timestamp, replacing the
+// receive time collectors usually stamp.
+type timestampProcessor struct {
+	cfg     model.LogProcessorConfig
+	layouts []string
+	loc     *time.Location
+}
+
+func newTimestampProcessor(cfg model.LogProcessorConfig) (processor, error) {
+	p := &timestampProcessor{cfg: cfg, layouts: cfg.Layouts, loc: time.UTC}
+	if len(p.layouts) == 0 {
+		p.layouts = defaultLayouts
+	}
+	if cfg.Location != "" {
+		loc, err := time.LoadLocation(cfg.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid location %q: %w", cfg.Location, err)
+		}
+		p.loc = loc
+	}
+	return p, nil
+}
+
+func (p *timestampProcessor) process(e *model.LogEntry) error {
+	var raw string
+	if p.cfg.Field != "" {
+		v, ok := input(e, p.cfg.Field)
+		if !ok {
+			return ErrNoMatch
+		}
+		raw = v
+	} else {
+		for _, k := range timestampKeys {
+			if v, ok := e.Fields[k]; ok && v != "" {
+				raw = v
+				break
+			}
+		}
+	}
+	if raw == "" {
+		return ErrNoMatch
+	}
+	ts, ok := ParseTimestamp(raw, p.layouts, p.loc)
+	if !ok {
+		return ErrNoMatch
+	}
+	e.Timestamp = ts
+	return nil
+}
+
+// ParseTimestamp tries each layout in turn. Layouts may be Go layouts or
+// the names rfc3339, rfc3339nano, httpdate, syslog, nginx_error, apache,
+// unix, unix_ms, unix_us and unix_ns; plain "unix" infers the unit from the
+// magnitude. Times without a year (syslog) get the current year.
+func ParseTimestamp(s string, layouts []string, loc *time.Location) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, l := range layouts {
+		switch l {
+		case "unix", "unix_ms", "unix_us", "unix_ns":
+			if ts, ok := parseEpoch(s, l); ok {
+				return ts, true
+			}
+			continue
+		}
+		layout := l
+		if named, ok := namedLayouts[l]; ok {
+			layout = named
+		}
+		ts, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+		if ts.Year() == 0 {
+			now := time.Now().In(loc)
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+		}
+		return ts, true
+	}
+	return time.Time{}, false
+}
+
+func parseEpoch(s, unit string) (time.Time, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return time.Time{}, false
+	}
+	if unit == "unix" {
+		switch {
+		case f > 1e17:
+			unit = "unix_ns"
+		case f > 1e14:
+			unit = "unix_us"
+		case f > 1e11:
+			unit = "unix_ms"
+		}
+	}
+	switch unit {
+	case "unix_ns":
+		return time.Unix(0, int64(f)).UTC(), true
+	case "unix_us":
+		return time.UnixMicro(int64(f)).UTC(), true
+	case "unix_ms":
+		return time.UnixMilli(int64(f)).UTC(), true
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC(), true
+}
@@ -0,0 +1,275 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/logsampler/sampler.go
+
+// Package logsampler thins noisy logs before they reach storage. Entries at
+// or above a severity threshold (error by default) are always kept; the rest
+// are sampled per stream, where a stream is the entry's Source and app name
+// and, optionally, its Drain pattern. A stream is sampled either at a fixed
+// 1-in-N rate or at a dynamic rate recomputed every window to approach a
+// target number of events per second. Kept entries carry their rate in
+// Fields[FieldSampleRate] so counts can be re-weighted downstream.
+package logsampler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/drain"
+	"github.com/aaronlmathis/gosight-shared/logpipeline"
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/severity"
+)
+
+// FieldSampleRate is the Fields key holding N for an entry kept at 1-in-N.
+// Entries that were not sampled (rate 1) are not annotated.
+const FieldSampleRate = "sample_rate"
+
+// Rule overrides the sampling of matching streams. Empty match fields match
+// anything; the first matching rule wins.
+type Rule struct {
+	Source  string `yaml:"source,omitempty" json:"source,omitempty"`
+	AppName string `yaml:"app_name,omitempty" json:"app_name,omitempty"`
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"` // drain pattern ID
+
+	Rate            int     `yaml:"rate,omitempty" json:"rate,omitempty"`
+	TargetPerSecond float64 `yaml:"target_per_second,omitempty" json:"target_per_second,omitempty"`
+}
+
+// Config controls a Sampler.
+type Config struct {
+	// KeepSeverity is the lowest severity that is never sampled. Default "error".
+	KeepSeverity string `yaml:"keep_severity,omitempty" json:"keep_severity,omitempty"`
+
+	// Rate keeps 1 in Rate entries of every stream. 0 or 1 keeps everything
+	// unless TargetPerSecond is set.
+	Rate int `yaml:"rate,omitempty" json:"rate,omitempty"`
+
+	// TargetPerSecond enables dynamic sampling: each stream's rate is set at
+	// the end of every Window so that it keeps about this many entries per
+	// second. Rate is used until the first window completes.
+	TargetPerSecond float64 `yaml:"target_per_second,omitempty" json:"target_per_second,omitempty"`
+
+	// Window is how often dynamic rates are recomputed. Default 10s.
+	Window time.Duration `yaml:"window,omitempty" json:"window,omitempty"`
+
+	// ByPattern splits streams by Fields[drain.FieldPatternID], so that one
+	// chatty template does not crowd out rarer messages from the same app.
+	ByPattern bool `yaml:"by_pattern,omitempty" json:"by_pattern,omitempty"`
+
+	Rules []Rule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// StreamStats reports one stream's activity in the current window.
+type StreamStats struct {
+	Source  string `json:"source"`
+	AppName string `json:"app_name"`
+	Pattern string `json:"pattern,omitempty"`
+	Rate    int    `json:"rate"`
+	Seen    uint64 `json:"seen"`
+	Kept    uint64 `json:"kept"`
+}
+
+// Sampler decides which entries to keep. It is safe for concurrent use.
+type Sampler struct {
+	cfg  Config
+	keep severity.Level
+	now  func() time.Time
+
+	mu          sync.Mutex
+	streams     map[streamKey]*stream
+	windowStart time.Time
+}
+
+type streamKey struct {
+	source, app, pattern string
+}
+
+type stream struct {
+	rule    *Rule
+	rate    int
+	counter uint64 // entries seen since the stream was created
+	seen    uint64 // entries seen this window
+	kept    uint64 // entries kept this window
+}
+
+// New creates a Sampler.
+func New(cfg Config) (*Sampler, error) {
+	if cfg.KeepSeverity == "" {
+		cfg.KeepSeverity = "error"
+	}
+	keep, ok := severity.Parse(cfg.KeepSeverity)
+	if !ok {
+		return nil, fmt.Errorf("logsampler: unknown keep_severity %q", cfg.KeepSeverity)
+	}
+	if cfg.Rate < 0 || cfg.TargetPerSecond < 0 {
+		return nil, fmt.Errorf("logsampler: rate and target_per_second must not be negative")
+	}
+	for _, r := range cfg.Rules {
+		if r.Rate < 0 || r.TargetPerSecond < 0 {
+			return nil, fmt.Errorf("logsampler: rule rate and target_per_second must not be negative")
+		}
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	return &Sampler{
+		cfg:     cfg,
+		keep:    keep,
+		now:     time.Now,
+		streams: map[streamKey]*stream{},
+	}, nil
+}
+
+// Sample reports whether an entry should be kept, annotating kept entries
+// that were sampled with FieldSampleRate.
+func (s *Sampler) Sample(e *model.LogEntry) bool {
+	if severity.Of(e).AtLeast(s.keep) {
+		return true
+	}
+	key := streamKey{source: e.Source, app: logpipeline.AppName(e)}
+	if s.cfg.ByPattern {
+		key.pattern = e.Fields[drain.FieldPatternID]
+	}
+
+	s.mu.Lock()
+	s.roll()
+	st := s.streams[key]
+	if st == nil {
+		st = &stream{rule: s.match(key)}
+		st.rate = s.initialRate(st.rule)
+		s.streams[key] = st
+	}
+	st.seen++
+	keep := st.counter%uint64(st.rate) == 0
+	st.counter++
+	if keep {
+		st.kept++
+	}
+	rate := st.rate
+	s.mu.Unlock()
+
+	if keep && rate > 1 {
+		if e.Fields == nil {
+			e.Fields = map[string]string{}
+		}
+		e.Fields[FieldSampleRate] = strconv.Itoa(rate)
+	}
+	return keep
+}
+
+// SamplePayload removes the entries Sample rejects and returns how many
+// were dropped.
+func (s *Sampler) SamplePayload(p *model.LogPayload) int {
+	kept := p.Logs[:0]
+	for i := range p.Logs {
+		if s.Sample(&p.Logs[i]) {
+			kept = append(kept, p.Logs[i])
+		}
+	}
+	dropped := len(p.Logs) - len(kept)
+	p.Logs = kept
+	return dropped
+}
+
+// Stats returns per-stream counts for the current window, busiest first.
+func (s *Sampler) Stats() []StreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]StreamStats, 0, len(s.streams))
+	for k, st := range s.streams {
+		out = append(out, StreamStats{
+			Source: k.source, AppName: k.app, Pattern: k.pattern,
+			Rate: st.rate, Seen: st.seen, Kept: st.kept,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Seen != out[j].Seen {
+			return out[i].Seen > out[j].Seen
+		}
+		return out[i].Source+out[i].AppName+out[i].Pattern < out[j].Source+out[j].AppName+out[j].Pattern
+	})
+	return out
+}
+
+// match returns the first rule matching a stream, or nil.
+func (s *Sampler) match(k streamKey) *Rule {
+	for i := range s.cfg.Rules {
+		r := &s.cfg.Rules[i]
+		if (r.Source == "" || r.Source == k.source) &&
+			(r.AppName == "" || r.AppName == k.app) &&
+			(r.Pattern == "" || r.Pattern == k.pattern) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *Sampler) initialRate(r *Rule) int {
+	rate := s.cfg.Rate
+	if r != nil && r.Rate > 0 {
+		rate = r.Rate
+	}
+	if rate < 1 {
+		rate = 1
+	}
+	return rate
+}
+
+func (s *Sampler) target(r *Rule) float64 {
+	if r != nil && (r.Rate > 0 || r.TargetPerSecond > 0) {
+		// A rule's own settings replace the global ones.
+		return r.TargetPerSecond
+	}
+	return s.cfg.TargetPerSecond
+}
+
+// roll closes the current window once it has elapsed: dynamic streams get a
+// new rate from the window's volume and idle streams are forgotten. Callers
+// hold s.mu.
+func (s *Sampler) roll() {
+	now := s.now()
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+		return
+	}
+	elapsed := now.Sub(s.windowStart)
+	if elapsed < s.cfg.Window {
+		return
+	}
+	for k, st := range s.streams {
+		if st.seen == 0 {
+			delete(s.streams, k)
+			continue
+		}
+		if target := s.target(st.rule); target > 0 {
+			budget := target * elapsed.Seconds()
+			st.rate = int(math.Max(1, math.Ceil(float64(st.seen)/budget)))
+		}
+		st.seen, st.kept = 0, 0
+	}
+	s.windowStart = now
+}
@@ -30,6 +30,37 @@ type CommandRequest struct {
 	CommandType string   `json:"command_type"`   // e.g., "shell" or "ansible"
 	CommandData string   `json:"command_data"`   // The actual shell command or playbook content
 	Args        []string `json:"args,omitempty"` // Optional arguments for shell commands
+
+	RequestID    string            `json:"request_id,omitempty"`    // Correlates output and results with this request
+	Timeout      time.Duration     `json:"timeout,omitempty"`       // Kill the command after this long; 0 = agent default
+	Env          map[string]string `json:"env,omitempty"`           // Extra environment variables
+	WorkingDir   string            `json:"working_dir,omitempty"`   // Working directory; empty = agent default
+	RunAsUser    string            `json:"run_as_user,omitempty"`   // Run as this user; empty = agent user
+	Stdin        []byte            `json:"stdin,omitempty"`         // Written to the command's standard input
+	StreamOutput bool              `json:"stream_output,omitempty"` // Send CommandOutputChunks while running
+
+	// Signing, see the command package. Set by the server, verified by the agent.
+	RequestedBy string    `json:"requested_by,omitempty"` // User who issued the command
+	Nonce       string    `json:"nonce,omitempty"`
+	IssuedAt    time.Time `json:"issued_at,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+	KeyID       string    `json:"key_id,omitempty"`
+	Signature   []byte    `json:"signature,omitempty"`
+}
+
+// Output stream names used by CommandOutputChunk.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// CommandOutputChunk is a piece of a running command's output.
+type CommandOutputChunk struct {
+	RequestID string    `json:"request_id"`
+	Stream    string    `json:"stream"` // StreamStdout or StreamStderr
+	Data      []byte    `json:"data"`
+	Sequence  int64     `json:"sequence"` // increases by one per chunk of a request, from 0
+	Timestamp time.Time `json:"timestamp"`
 }
 
 type CommandResult struct {
@@ -38,20 +69,44 @@ type CommandResult struct {
 	Success      bool   `json:"success"`
 	ErrorMessage string `json:"error_message,omitempty"`
 	Timestamp    string `json:"timestamp"` // ISO format
+
+	RequestID  string `json:"request_id,omitempty"`
+	Status     string `json:"status,omitempty"` // CommandStatus* value
+	ExitCode   int    `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
 }
 
+// CommandResult statuses.
+const (
+	CommandStatusSuccess  = "success"
+	CommandStatusFailed   = "failed"
+	CommandStatusTimeout  = "timeout"
+	CommandStatusCanceled = "canceled"
+	CommandStatusSkipped  = "skipped" // not run, e.g. after a fan-out stopped
+)
+
 type CommandResponse struct {
 	Success      bool
 	Output       string
 	ErrorMessage string
+
+	RequestID string
+	ExitCode  int
+	Duration  time.Duration
+	StartedAt time.Time
+	TimedOut  bool
 }
 
 type ShellCommandResult struct {
 	Output       string        // Full stdout and stderr combined
+	Stdout       string        // Standard output alone, when captured separately
+	Stderr       string        // Standard error alone, when captured separately
+	Truncated    bool          // Output exceeded the runner's size cap
 	ExitCode     int           // Actual exit code (0 = success)
 	ErrorMessage string        // Error description if command failed (e.g. exec errors)
 	Duration     time.Duration // How long it took to run
 	StartedAt    time.Time     // Optional: when the command started
+	TimedOut     bool          // Killed after exceeding its timeout
 }
 type AnsibleCommandResult struct {
 	Output       string
@@ -0,0 +1,102 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/model/commandfanout.go
+
+package model
+
+import "time"
+
+// FanOutRequest runs one command on every endpoint matched by Target.
+type FanOutRequest struct {
+	ID      string         `yaml:"id,omitempty" json:"id,omitempty"` // generated when empty
+	Command CommandRequest `yaml:"command" json:"command"`           // RequestID is set per endpoint, AgentID by the dispatcher
+	Target  TargetSelector `yaml:"target" json:"target"`
+
+	// Concurrency is the most endpoints running the command at once.
+	// Default 10.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+
+	// MaxFailurePercent stops starting new endpoints once more than this
+	// share of the targeted endpoints has failed. 0 disables the check.
+	MaxFailurePercent float64 `yaml:"max_failure_percent,omitempty" json:"max_failure_percent,omitempty"`
+
+	Canary Canary `yaml:"canary,omitempty" json:"canary,omitempty"`
+}
+
+// TargetSelector picks endpoints by ID and labels. All set conditions must
+// hold; an empty selector matches every endpoint.
+type TargetSelector struct {
+	EndpointIDs []string           `yaml:"endpoint_ids,omitempty" json:"endpoint_ids,omitempty"`
+	Labels      map[string]string  `yaml:"labels,omitempty" json:"labels,omitempty"`           // exact match; "*" only requires the key
+	Expressions []LabelRequirement `yaml:"expressions,omitempty" json:"expressions,omitempty"` // set-based conditions
+	OnlineOnly  bool               `yaml:"online_only,omitempty" json:"online_only,omitempty"` // skip endpoints whose Status is not "online"
+}
+
+// LabelRequirement is a set-based label condition, as in Kubernetes label
+// selectors.
+type LabelRequirement struct {
+	Key      string   `yaml:"key" json:"key"`
+	Operator string   `yaml:"operator" json:"operator"` // LabelOp* value
+	Values   []string `yaml:"values,omitempty" json:"values,omitempty"`
+}
+
+// LabelRequirement operators.
+const (
+	LabelOpIn           = "in"
+	LabelOpNotIn        = "not_in"
+	LabelOpExists       = "exists"
+	LabelOpDoesNotExist = "does_not_exist"
+)
+
+// Canary runs the command on a first batch of endpoints. The rest are only
+// started once every canary succeeded. The batch size is the larger of
+// Count and Percent of the targets.
+type Canary struct {
+	Count   int     `yaml:"count,omitempty" json:"count,omitempty"`
+	Percent float64 `yaml:"percent,omitempty" json:"percent,omitempty"`
+}
+
+// FanOutResult holds one CommandResult per targeted endpoint, in target
+// order, and a summary of them.
+type FanOutResult struct {
+	ID        string          `json:"id"`
+	StartedAt time.Time       `json:"started_at"`
+	Results   []CommandResult `json:"results"`
+	Summary   FanOutSummary   `json:"summary"`
+}
+
+// FanOutSummary aggregates the results of a fan-out.
+type FanOutSummary struct {
+	Targeted       int         `json:"targeted"`
+	Succeeded      int         `json:"succeeded"`
+	Failed         int         `json:"failed"`
+	TimedOut       int         `json:"timed_out"`
+	Canceled       int         `json:"canceled"`
+	Skipped        int         `json:"skipped"`
+	FailurePercent float64     `json:"failure_percent"` // failed, timed out and canceled over Targeted
+	ExitCodes      map[int]int `json:"exit_codes,omitempty"`
+	AvgDurationMs  int64       `json:"avg_duration_ms"`
+	MaxDurationMs  int64       `json:"max_duration_ms"`
+	DurationMs     int64       `json:"duration_ms"` // wall time of the whole fan-out
+	Stopped        bool        `json:"stopped"`
+	StopReason     string      `json:"stop_reason,omitempty"`
+}
@@ -0,0 +1,50 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/model/commandpolicy.go
+
+package model
+
+// CommandPolicy is the agent-side allowlist for remote commands. Rules are
+// checked in order and the first match decides; a request that matches no
+// rule is rejected.
+type CommandPolicy struct {
+	// AllowUnsigned accepts requests without a valid signature. Only meant
+	// for development setups without a pinned server key.
+	AllowUnsigned bool `yaml:"allow_unsigned,omitempty" json:"allow_unsigned,omitempty"`
+
+	// MaxAuditEvents caps the audit events buffered between DrainEvents
+	// calls; the oldest are dropped beyond it. Default 1000.
+	MaxAuditEvents int `yaml:"max_audit_events,omitempty" json:"max_audit_events,omitempty"`
+
+	Rules []CommandPolicyRule `yaml:"rules" json:"rules"`
+}
+
+// CommandPolicyRule matches requests by type, command line and users. Empty
+// lists match anything.
+type CommandPolicyRule struct {
+	Name     string   `yaml:"name" json:"name"`
+	Types    []string `yaml:"types,omitempty" json:"types,omitempty"`       // "shell", "ansible"
+	Commands []string `yaml:"commands,omitempty" json:"commands,omitempty"` // regexes, fully anchored, over the command and its args
+	Users    []string `yaml:"users,omitempty" json:"users,omitempty"`       // CommandRequest.RequestedBy
+	RunAs    []string `yaml:"run_as,omitempty" json:"run_as,omitempty"`     // CommandRequest.RunAsUser; "" is the agent user
+	Deny     bool     `yaml:"deny,omitempty" json:"deny,omitempty"`         // reject instead of allow
+}
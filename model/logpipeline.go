@@ -0,0 +1,86 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/model/logpipeline.go
+
+package model
+
+// LogPipelineSet is an ordered list of log pipelines. Each entry is handled
+// by the first pipeline whose selectors match it.
+type LogPipelineSet struct {
+	Pipelines []LogPipeline `yaml:"pipelines" json:"pipelines"`
+}
+
+// LogPipeline runs its processors, in order, over entries selected by
+// Source and AppName. Empty selectors match everything.
+type LogPipeline struct {
+	Name       string               `yaml:"name" json:"name"`
+	Sources    []string             `yaml:"sources,omitempty" json:"sources,omitempty"`     // LogEntry.Source, e.g. "journald", "syslog"
+	AppNames   []string             `yaml:"app_names,omitempty" json:"app_names,omitempty"` // Meta.AppName or Fields["app_name"], e.g. "nginx"
+	Processors []LogProcessorConfig `yaml:"processors" json:"processors"`
+}
+
+// LogProcessorConfig configures one pipeline step. Which options apply
+// depends on Type.
+type LogProcessorConfig struct {
+	Type string `yaml:"type" json:"type"` // json, logfmt, regex, grok, timestamp, severity, category
+
+	// Field is the input: "message" (default), "body", or a Fields key.
+	Field string `yaml:"field,omitempty" json:"field,omitempty"`
+
+	// Target receives extracted values: "fields" (default) or "attributes".
+	Target string `yaml:"target,omitempty" json:"target,omitempty"`
+
+	// Prefix is prepended to extracted keys.
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// Patterns are tried in order by regex and grok; the first match wins.
+	// Regex patterns use named groups, grok patterns use %{PATTERN:field}.
+	Patterns []string `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+
+	// Definitions adds or overrides grok patterns by name.
+	Definitions map[string]string `yaml:"definitions,omitempty" json:"definitions,omitempty"`
+
+	// Layouts are the timestamp formats to try: Go layouts or one of
+	// rfc3339, rfc3339nano, httpdate, syslog, unix, unix_ms, unix_us, unix_ns.
+	Layouts []string `yaml:"layouts,omitempty" json:"layouts,omitempty"`
+
+	// Location is the IANA zone for timestamps without an offset.
+	Location string `yaml:"location,omitempty" json:"location,omitempty"`
+
+	// Mapping maps raw values to normalized ones: vendor levels to level
+	// names for the severity processor, application or source names to
+	// categories for the category processor.
+	Mapping map[string]string `yaml:"mapping,omitempty" json:"mapping,omitempty"`
+
+	// Rules assign a category when their regex matches (category processor).
+	Rules []LogCategoryRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+
+	// Overwrite replaces values that are already set.
+	Overwrite bool `yaml:"overwrite,omitempty" json:"overwrite,omitempty"`
+}
+
+// LogCategoryRule assigns Category when Regex matches Field.
+type LogCategoryRule struct {
+	Category string `yaml:"category" json:"category"`               // one of the standardized log categories
+	Field    string `yaml:"field,omitempty" json:"field,omitempty"` // default "message"
+	Regex    string `yaml:"regex" json:"regex"`
+}
@@ -83,6 +83,10 @@ type Metric struct {
     //   "cumulative" or "delta"
     AggregationTemporality string `json:"aggregation_temporality,omitempty"`
 
+    // For "sum" metrics, marks an OTLP non-monotonic sum (UpDownCounter),
+    // which may go down. Sums are monotonic counters by default.
+    NonMonotonic bool `json:"non_monotonic,omitempty"`
+
     // Each metric may carry zero or more DataPoints (usually at least one).
     DataPoints []DataPoint `json:"data_points,omitempty"`
 
@@ -0,0 +1,51 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/model/relabel.go
+
+package model
+
+// RelabelRuleSet groups relabel rules by namespace. Rules with an empty or "*"
+// namespace apply to every namespace and run before namespace-specific rules.
+type RelabelRuleSet struct {
+	Namespaces []NamespaceRelabel `yaml:"namespaces" json:"namespaces"`
+}
+
+// NamespaceRelabel holds the relabel rules for one namespace, split by the
+// kind of telemetry they run over.
+type NamespaceRelabel struct {
+	Namespace string          `yaml:"namespace" json:"namespace"`                 // e.g. "System", "AWS/EC2", "*"
+	Metrics   []RelabelConfig `yaml:"metrics,omitempty" json:"metrics,omitempty"` // model.Metric data point attributes
+	Logs      []RelabelConfig `yaml:"logs,omitempty" json:"logs,omitempty"`       // LogEntry.Labels
+	Traces    []RelabelConfig `yaml:"traces,omitempty" json:"traces,omitempty"`   // TraceSpan.Attributes
+}
+
+// RelabelConfig is a single Prometheus-style relabel rule.
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#relabel_config
+type RelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels,omitempty" json:"source_labels,omitempty"` // labels concatenated as input
+	Separator    string   `yaml:"separator,omitempty" json:"separator,omitempty"`         // default ";"
+	Regex        string   `yaml:"regex,omitempty" json:"regex,omitempty"`                 // default "(.*)", fully anchored
+	Modulus      uint64   `yaml:"modulus,omitempty" json:"modulus,omitempty"`             // for hashmod
+	TargetLabel  string   `yaml:"target_label,omitempty" json:"target_label,omitempty"`   // for replace, hashmod, lowercase, uppercase
+	Replacement  *string  `yaml:"replacement,omitempty" json:"replacement,omitempty"`     // default "$1"
+	Action       string   `yaml:"action,omitempty" json:"action,omitempty"`               // replace, keep, drop, hashmod, labelmap, labeldrop, labelkeep, lowercase, uppercase
+}
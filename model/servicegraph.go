@@ -0,0 +1,66 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/model/servicegraph.go
+
+package model
+
+import "time"
+
+// ServiceGraph is a service dependency map for one time window, derived
+// from trace spans.
+type ServiceGraph struct {
+	Start time.Time     `json:"start"`
+	End   time.Time     `json:"end"`
+	Nodes []ServiceNode `json:"nodes"`
+	Edges []ServiceEdge `json:"edges"`
+}
+
+// ServiceNode is a service in a ServiceGraph.
+type ServiceNode struct {
+	Name string `json:"name"`
+
+	// Virtual is set for callees that do not report spans themselves, such
+	// as databases or services named only by a client's peer.service.
+	Virtual bool `json:"virtual,omitempty"`
+
+	Calls  uint64 `json:"calls"`  // incoming calls
+	Errors uint64 `json:"errors"` // incoming calls that failed
+}
+
+// ServiceEdge is a directed caller -> callee relationship.
+type ServiceEdge struct {
+	Source         string `json:"source"`
+	Target         string `json:"target"`
+	ConnectionType string `json:"connection_type,omitempty"` // "", "database", "messaging" or "virtual"
+
+	Calls     uint64  `json:"calls"`
+	Errors    uint64  `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+
+	LatencyP50Ms float64 `json:"latency_p50_ms"`
+	LatencyP95Ms float64 `json:"latency_p95_ms"`
+	LatencyP99Ms float64 `json:"latency_p99_ms"`
+
+	// Latency is the call duration histogram in milliseconds, kept so graphs
+	// from several windows can be merged.
+	Latency DataPoint `json:"latency"`
+}
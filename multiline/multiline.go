@@ -0,0 +1,268 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/multiline/multiline.go
+
+// Package multiline joins log entries that belong to one logical record,
+// such as Java, Python, Node.js or Go stack traces emitted line by line.
+// Entries are grouped per stream (Source, container and file path) so that
+// interleaved streams never mix.
+package multiline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Preset holds the patterns for a known log format.
+type Preset struct {
+	Start    string
+	Continue string
+}
+
+// Presets are the built-in patterns, selected with Config.Preset.
+var Presets = map[string]Preset{
+	// A log message or "Exception in thread ..." followed by the exception
+	// line (as logback and log4j print it), "\tat ...", "Caused by: ..."
+	// and "\t... 12 more".
+	"java": {Continue: `^(?:\s+at\s|\s+\.\.\.\s+\d+\s+more|\s*Caused by:|\s*Suppressed:|\s+|[\w$.]+(?:Exception|Error|Throwable)\b(?::.*)?$)`},
+
+	// "Traceback (most recent call last):", indented frames, then the
+	// exception line, possibly chained.
+	"python": {Continue: `^(?:\s|Traceback \(most recent call last\):|During handling of the above exception|The above exception was the direct cause|[A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt|Warning)\b(?::.*)?$)`},
+
+	// "panic: ...", blank lines, "goroutine N [running]:", function lines
+	// and indented file:line frames.
+	"go": {Continue: `^(?:\s|$|goroutine \d+ \[|created by |\[signal |exit status |[\w.\-/*()]+\(.*\)$)`},
+
+	// "Error: ..." followed by "    at fn (file:line:col)".
+	"nodejs": {Continue: `^\s+at\s`},
+}
+
+// Config controls how lines are joined. A line continues the pending record
+// unless it matches Start; when Continue is set it must also match Continue.
+type Config struct {
+	Preset   string // key of Presets; Start/Continue override it
+	Start    string // regex for the first line of a record
+	Continue string // regex for continuation lines
+
+	MaxLines     int           // lines per record before it is flushed; default 500
+	MaxBytes     int           // message bytes per record before it is flushed; default 64 KiB
+	FlushTimeout time.Duration // idle time before a pending record is flushed; default 2s
+}
+
+// Combiner joins entries into multiline records. It is safe for concurrent
+// use.
+type Combiner struct {
+	cfg     Config
+	start   *regexp.Regexp
+	cont    *regexp.Regexp
+	now     func() time.Time
+	mu      sync.Mutex
+	pending map[string]*record
+	order   []string
+}
+
+type record struct {
+	entry   model.LogEntry
+	lines   []string
+	bodies  []string
+	bytes   int
+	updated time.Time
+}
+
+// NewCombiner compiles the configured patterns.
+func NewCombiner(cfg Config) (*Combiner, error) {
+	if cfg.Preset != "" {
+		p, ok := Presets[strings.ToLower(cfg.Preset)]
+		if !ok {
+			return nil, fmt.Errorf("multiline: unknown preset %q", cfg.Preset)
+		}
+		if cfg.Start == "" {
+			cfg.Start = p.Start
+		}
+		if cfg.Continue == "" {
+			cfg.Continue = p.Continue
+		}
+	}
+	if cfg.Start == "" && cfg.Continue == "" {
+		return nil, fmt.Errorf("multiline: a preset, start or continue pattern is required")
+	}
+	if cfg.MaxLines <= 0 {
+		cfg.MaxLines = 500
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 64 * 1024
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = 2 * time.Second
+	}
+
+	c := &Combiner{cfg: cfg, now: time.Now, pending: map[string]*record{}}
+	var err error
+	if cfg.Start != "" {
+		if c.start, err = regexp.Compile(cfg.Start); err != nil {
+			return nil, fmt.Errorf("multiline: invalid start pattern: %w", err)
+		}
+	}
+	if cfg.Continue != "" {
+		if c.cont, err = regexp.Compile(cfg.Continue); err != nil {
+			return nil, fmt.Errorf("multiline: invalid continue pattern: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// StreamKey identifies the stream an entry belongs to.
+func StreamKey(e *model.LogEntry) string {
+	key := e.Source
+	if e.Meta != nil {
+		key += "\x00" + e.Meta.ContainerID + "\x00" + e.Meta.Path
+	}
+	return key
+}
+
+// Add feeds one entry and returns any records it completed. The entry itself
+// is held until a later line, a cap or the flush timeout completes it.
+func (c *Combiner) Add(e model.LogEntry) []model.LogEntry {
+	line := e.Message
+	if line == "" {
+		line = e.Body
+	}
+	key := StreamKey(&e)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []model.LogEntry
+	if r, ok := c.pending[key]; ok {
+		if c.continues(line) && len(r.lines) < c.cfg.MaxLines && r.bytes+len(line)+1 <= c.cfg.MaxBytes {
+			r.lines = append(r.lines, line)
+			r.bodies = append(r.bodies, e.Body)
+			r.bytes += len(line) + 1
+			r.updated = now
+			return nil
+		}
+		// Either a new record starts or the cap was reached; in the latter
+		// case the line begins a new record of its own.
+		out = append(out, r.finish())
+		c.remove(key)
+	}
+	c.pending[key] = &record{entry: e, lines: []string{line}, bodies: []string{e.Body}, bytes: len(line), updated: now}
+	c.order = append(c.order, key)
+	return out
+}
+
+// AddPayload runs every entry of a payload through Add and replaces the
+// payload's logs with the records completed so far. Entries without Meta
+// inherit the payload Meta so they are keyed by the right stream.
+func (c *Combiner) AddPayload(p *model.LogPayload) {
+	var out []model.LogEntry
+	for _, e := range p.Logs {
+		if e.Meta == nil {
+			e.Meta = p.Meta
+		}
+		out = append(out, c.Add(e)...)
+	}
+	p.Logs = out
+}
+
+// Flush returns records that have been idle for at least FlushTimeout.
+func (c *Combiner) Flush() []model.LogEntry {
+	cutoff := c.now().Add(-c.cfg.FlushTimeout)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []model.LogEntry
+	for _, key := range append([]string(nil), c.order...) {
+		if r := c.pending[key]; !r.updated.After(cutoff) {
+			out = append(out, r.finish())
+			c.remove(key)
+		}
+	}
+	return out
+}
+
+// FlushAll returns every pending record, e.g. on shutdown.
+func (c *Combiner) FlushAll() []model.LogEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]model.LogEntry, 0, len(c.order))
+	for _, key := range c.order {
+		out = append(out, c.pending[key].finish())
+	}
+	c.pending = map[string]*record{}
+	c.order = nil
+	return out
+}
+
+// Pending returns the number of streams with a record in progress.
+func (c *Combiner) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+func (c *Combiner) continues(line string) bool {
+	if c.start != nil && c.start.MatchString(line) {
+		return false
+	}
+	return c.cont == nil || c.cont.MatchString(line)
+}
+
+// remove must be called with c.mu held.
+func (c *Combiner) remove(key string) {
+	delete(c.pending, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// finish builds the combined entry. It keeps the first entry's timestamp,
+// severity and metadata and records the line count in Fields.
+func (r *record) finish() model.LogEntry {
+	e := r.entry
+	if len(r.lines) == 1 {
+		return e
+	}
+	e.Message = strings.Join(r.lines, "\n")
+	if e.Body != "" {
+		e.Body = strings.Join(r.bodies, "\n")
+	}
+	fields := make(map[string]string, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+	fields["multiline.lines"] = strconv.Itoa(len(r.lines))
+	e.Fields = fields
+	return e
+}
@@ -0,0 +1,102 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/multiline/multiline_test.go
+
+package multiline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+func combine(t *testing.T, preset string, lines ...string) []model.LogEntry {
+	t.Helper()
+	c, err := NewCombiner(Config{Preset: preset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []model.LogEntry
+	for _, l := range lines {
+		out = append(out, c.Add(model.LogEntry{Source: "app", Message: l})...)
+	}
+	return append(out, c.FlushAll()...)
+}
+
+func messages(entries []model.LogEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Message
+	}
+	return out
+}
+
+func TestJavaLogWithException(t *testing.T) {
+	lines := []string{
+		"2025-05-14 09:12:41.527 ERROR [main] c.e.Handler - Request failed",
+		"java.lang.IllegalStateException: boom",
+		"\tat com.example.Handler.handle(Handler.java:42)",
+		"\tat com.example.Server.run(Server.java:17)",
+		"Caused by: java.io.IOException: broken pipe",
+		"\tat com.example.Conn.write(Conn.java:88)",
+		"\t... 2 more",
+		"2025-05-14 09:12:41.530 INFO  [main] c.e.Server - Next request",
+	}
+	out := combine(t, "java", lines...)
+	if len(out) != 2 {
+		t.Fatalf("got %d records, want 2: %q", len(out), messages(out))
+	}
+	if want := strings.Join(lines[:7], "\n"); out[0].Message != want {
+		t.Errorf("first record = %q, want %q", out[0].Message, want)
+	}
+	if out[0].Fields["multiline.lines"] != "7" {
+		t.Errorf("multiline.lines = %q, want 7", out[0].Fields["multiline.lines"])
+	}
+	if out[1].Message != lines[7] {
+		t.Errorf("second record = %q", out[1].Message)
+	}
+}
+
+func TestJavaUncaughtException(t *testing.T) {
+	out := combine(t, "java",
+		`Exception in thread "main" java.lang.NullPointerException`,
+		"\tat Main.main(Main.java:5)",
+		"Error: could not start",
+	)
+	if len(out) != 2 || out[0].Fields["multiline.lines"] != "2" {
+		t.Errorf("records = %q", messages(out))
+	}
+}
+
+func TestPythonTraceback(t *testing.T) {
+	out := combine(t, "python",
+		"Traceback (most recent call last):",
+		`  File "app.py", line 3, in <module>`,
+		"    main()",
+		"ValueError: bad value",
+		"INFO done",
+	)
+	if len(out) != 2 || out[0].Fields["multiline.lines"] != "4" {
+		t.Errorf("records = %q", messages(out))
+	}
+}
@@ -0,0 +1,132 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/otlp/logs.go
+
+package otlp
+
+import (
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/severity"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// LogsToModel converts an OTLP logs export into one LogPayload per
+// resource/scope pair.
+func LogsToModel(req *collogspb.ExportLogsServiceRequest) []model.LogPayload {
+	var out []model.LogPayload
+	now := time.Now()
+	for _, rl := range req.GetResourceLogs() {
+		for _, sl := range rl.GetScopeLogs() {
+			meta := ResourceToMeta(rl.GetResource(), sl.GetScope())
+			payload := model.LogPayload{
+				AgentID:    meta.AgentID,
+				HostID:     meta.HostID,
+				Hostname:   meta.Hostname,
+				EndpointID: meta.EndpointID,
+				Timestamp:  now,
+				Meta:       meta,
+			}
+			for _, rec := range sl.GetLogRecords() {
+				payload.Logs = append(payload.Logs, logToModel(rec, meta))
+			}
+			out = append(out, payload)
+		}
+	}
+	return out
+}
+
+func logToModel(rec *logspb.LogRecord, meta *model.Meta) model.LogEntry {
+	body := anyToString(rec.GetBody())
+	entry := model.LogEntry{
+		Timestamp:         nanosToTime(rec.GetTimeUnixNano()),
+		ObservedTimestamp: nanosToTime(rec.GetObservedTimeUnixNano()),
+		SeverityText:      rec.GetSeverityText(),
+		SeverityNumber:    int32(rec.GetSeverityNumber()),
+		Name:              rec.GetEventName(),
+		Body:              body,
+		Message:           body,
+		TraceID:           idString(rec.GetTraceId()),
+		SpanID:            idString(rec.GetSpanId()),
+		Flags:             rec.GetFlags(),
+		Source:            Source,
+		Attributes:        kvsToInterfaceMap(rec.GetAttributes()),
+		Meta:              meta,
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = entry.ObservedTimestamp
+	}
+	severity.Normalize(&entry)
+	return entry
+}
+
+// LogsFromModel converts GoSight log payloads into an OTLP export request,
+// one ResourceLogs per payload.
+func LogsFromModel(payloads []model.LogPayload) *collogspb.ExportLogsServiceRequest {
+	req := &collogspb.ExportLogsServiceRequest{}
+	for _, p := range payloads {
+		scope := &logspb.ScopeLogs{Scope: MetaToScope(p.Meta)}
+		for i := range p.Logs {
+			scope.LogRecords = append(scope.LogRecords, logFromModel(&p.Logs[i]))
+		}
+		req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{
+			Resource:  MetaToResource(p.Meta),
+			ScopeLogs: []*logspb.ScopeLogs{scope},
+		})
+	}
+	return req
+}
+
+func logFromModel(e *model.LogEntry) *logspb.LogRecord {
+	body := e.Body
+	if body == "" {
+		body = e.Message
+	}
+	sev := severity.Of(e)
+	text := e.SeverityText
+	if text == "" {
+		text = sev.Text()
+	}
+
+	attrs := interfaceMapToKVs(e.Attributes)
+	attrs = append(attrs, mapToKVs(e.Fields)...)
+	attrs = append(attrs, mapToKVs(e.Labels)...)
+	if e.Category != "" {
+		attrs = append(attrs, stringKV("log.category", e.Category))
+	}
+
+	return &logspb.LogRecord{
+		TimeUnixNano:         timeToNanos(e.Timestamp),
+		ObservedTimeUnixNano: timeToNanos(e.ObservedTimestamp),
+		SeverityNumber:       logspb.SeverityNumber(sev.Number()),
+		SeverityText:         text,
+		EventName:            e.Name,
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+		Attributes:           attrs,
+		Flags:                e.Flags,
+		TraceId:              idBytes(e.TraceID),
+		SpanId:               idBytes(e.SpanID),
+	}
+}
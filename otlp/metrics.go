@@ -0,0 +1,332 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/otlp/metrics.go
+
+package otlp
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/histogram"
+	"github.com/aaronlmathis/gosight-shared/model"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// Source is set on every model.Metric produced from OTLP.
+const Source = "otlp"
+
+// MetricsToModel converts an OTLP metrics export into one MetricPayload per
+// resource/scope pair. Exponential histograms are converted to explicit
+// histograms using their own bucket edges, so no counts are redistributed.
+func MetricsToModel(req *colmetricspb.ExportMetricsServiceRequest) []model.MetricPayload {
+	var out []model.MetricPayload
+	now := time.Now()
+	for _, rm := range req.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			meta := ResourceToMeta(rm.GetResource(), sm.GetScope())
+			payload := model.MetricPayload{
+				AgentID:    meta.AgentID,
+				HostID:     meta.HostID,
+				Hostname:   meta.Hostname,
+				EndpointID: meta.EndpointID,
+				Meta:       meta,
+				Timestamp:  now,
+			}
+			for _, m := range sm.GetMetrics() {
+				metric := metricToModel(m)
+				metric.Meta = meta
+				payload.Metrics = append(payload.Metrics, metric)
+			}
+			out = append(out, payload)
+		}
+	}
+	return out
+}
+
+func metricToModel(m *metricspb.Metric) model.Metric {
+	out := model.Metric{
+		Name:        m.GetName(),
+		Description: m.GetDescription(),
+		Unit:        m.GetUnit(),
+		Source:      Source,
+	}
+
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		out.DataType = "gauge"
+		for _, dp := range data.Gauge.GetDataPoints() {
+			out.DataPoints = append(out.DataPoints, numberPoint(dp))
+		}
+	case *metricspb.Metric_Sum:
+		out.DataType = "sum"
+		out.AggregationTemporality = temporality(data.Sum.GetAggregationTemporality())
+		out.NonMonotonic = !data.Sum.GetIsMonotonic()
+		for _, dp := range data.Sum.GetDataPoints() {
+			out.DataPoints = append(out.DataPoints, numberPoint(dp))
+		}
+	case *metricspb.Metric_Histogram:
+		out.DataType = "histogram"
+		out.AggregationTemporality = temporality(data.Histogram.GetAggregationTemporality())
+		for _, dp := range data.Histogram.GetDataPoints() {
+			out.DataPoints = append(out.DataPoints, model.DataPoint{
+				Attributes:     kvsToMap(dp.GetAttributes()),
+				StartTimestamp: nanosToTime(dp.GetStartTimeUnixNano()),
+				Timestamp:      nanosToTime(dp.GetTimeUnixNano()),
+				Count:          dp.GetCount(),
+				Sum:            dp.GetSum(),
+				BucketCounts:   dp.GetBucketCounts(),
+				ExplicitBounds: dp.GetExplicitBounds(),
+				Exemplars:      exemplars(dp.GetExemplars()),
+			})
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		out.DataType = "histogram"
+		out.AggregationTemporality = temporality(data.ExponentialHistogram.GetAggregationTemporality())
+		for _, dp := range data.ExponentialHistogram.GetDataPoints() {
+			out.DataPoints = append(out.DataPoints, exponentialPoint(dp))
+		}
+	case *metricspb.Metric_Summary:
+		out.DataType = "summary"
+		for _, dp := range data.Summary.GetDataPoints() {
+			point := model.DataPoint{
+				Attributes:     kvsToMap(dp.GetAttributes()),
+				StartTimestamp: nanosToTime(dp.GetStartTimeUnixNano()),
+				Timestamp:      nanosToTime(dp.GetTimeUnixNano()),
+				Count:          dp.GetCount(),
+				Sum:            dp.GetSum(),
+			}
+			for _, q := range dp.GetQuantileValues() {
+				point.QuantileValues = append(point.QuantileValues, model.QuantileValue{Quantile: q.GetQuantile(), Value: q.GetValue()})
+			}
+			out.DataPoints = append(out.DataPoints, point)
+		}
+	}
+	return out
+}
+
+func numberPoint(dp *metricspb.NumberDataPoint) model.DataPoint {
+	v := dp.GetAsDouble()
+	if _, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		v = float64(dp.GetAsInt())
+	}
+	return model.DataPoint{
+		Attributes:     kvsToMap(dp.GetAttributes()),
+		StartTimestamp: nanosToTime(dp.GetStartTimeUnixNano()),
+		Timestamp:      nanosToTime(dp.GetTimeUnixNano()),
+		Value:          v,
+		Exemplars:      exemplars(dp.GetExemplars()),
+	}
+}
+
+func exponentialPoint(dp *metricspb.ExponentialHistogramDataPoint) model.DataPoint {
+	eh := &histogram.ExponentialHistogram{
+		Scale:         dp.GetScale(),
+		ZeroCount:     dp.GetZeroCount(),
+		ZeroThreshold: dp.GetZeroThreshold(),
+		Count:         dp.GetCount(),
+		Sum:           dp.GetSum(),
+		Positive: histogram.ExponentialBuckets{
+			Offset:       dp.GetPositive().GetOffset(),
+			BucketCounts: dp.GetPositive().GetBucketCounts(),
+		},
+		Negative: histogram.ExponentialBuckets{
+			Offset:       dp.GetNegative().GetOffset(),
+			BucketCounts: dp.GetNegative().GetBucketCounts(),
+		},
+	}
+
+	point := model.DataPoint{Count: eh.Count, Sum: eh.Sum}
+	if explicit, err := eh.ToExplicit(eh.ExplicitBounds()); err == nil {
+		point = *explicit
+	}
+	point.Attributes = kvsToMap(dp.GetAttributes())
+	point.StartTimestamp = nanosToTime(dp.GetStartTimeUnixNano())
+	point.Timestamp = nanosToTime(dp.GetTimeUnixNano())
+	point.Exemplars = exemplars(dp.GetExemplars())
+	return point
+}
+
+func exemplars(in []*metricspb.Exemplar) []model.Exemplar {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]model.Exemplar, 0, len(in))
+	for _, e := range in {
+		v := e.GetAsDouble()
+		if _, ok := e.GetValue().(*metricspb.Exemplar_AsInt); ok {
+			v = float64(e.GetAsInt())
+		}
+		out = append(out, model.Exemplar{
+			Value:              v,
+			Timestamp:          nanosToTime(e.GetTimeUnixNano()),
+			TraceID:            idString(e.GetTraceId()),
+			SpanID:             idString(e.GetSpanId()),
+			FilteredAttributes: kvsToMap(e.GetFilteredAttributes()),
+		})
+	}
+	return out
+}
+
+func temporality(t metricspb.AggregationTemporality) string {
+	switch t {
+	case metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA:
+		return "delta"
+	case metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE:
+		return "cumulative"
+	}
+	return ""
+}
+
+// MetricsFromModel converts GoSight metric payloads into an OTLP export
+// request, one ResourceMetrics per payload. A metric's own Meta, when set,
+// takes precedence over the payload Meta for its resource.
+func MetricsFromModel(payloads []model.MetricPayload) *colmetricspb.ExportMetricsServiceRequest {
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	for _, p := range payloads {
+		groups := map[*model.Meta][]*metricspb.Metric{}
+		var order []*model.Meta
+		for i := range p.Metrics {
+			m := &p.Metrics[i]
+			meta := p.Meta
+			if m.Meta != nil {
+				meta = m.Meta
+			}
+			if _, ok := groups[meta]; !ok {
+				order = append(order, meta)
+			}
+			groups[meta] = append(groups[meta], metricFromModel(m))
+		}
+		for _, meta := range order {
+			req.ResourceMetrics = append(req.ResourceMetrics, &metricspb.ResourceMetrics{
+				Resource: MetaToResource(meta),
+				ScopeMetrics: []*metricspb.ScopeMetrics{{
+					Scope:   MetaToScope(meta),
+					Metrics: groups[meta],
+				}},
+			})
+		}
+	}
+	return req
+}
+
+func metricFromModel(m *model.Metric) *metricspb.Metric {
+	out := &metricspb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	temp := metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	if strings.EqualFold(m.AggregationTemporality, "delta") {
+		temp = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+
+	switch strings.ToLower(m.DataType) {
+	case "sum", "counter":
+		sum := &metricspb.Sum{AggregationTemporality: temp, IsMonotonic: !m.NonMonotonic}
+		for i := range m.DataPoints {
+			sum.DataPoints = append(sum.DataPoints, numberPointFromModel(&m.DataPoints[i]))
+		}
+		out.Data = &metricspb.Metric_Sum{Sum: sum}
+	case "histogram":
+		h := &metricspb.Histogram{AggregationTemporality: temp}
+		for i := range m.DataPoints {
+			dp := &m.DataPoints[i]
+			sum := dp.Sum
+			h.DataPoints = append(h.DataPoints, &metricspb.HistogramDataPoint{
+				Attributes:        mapToKVs(dp.Attributes),
+				StartTimeUnixNano: timeToNanos(dp.StartTimestamp),
+				TimeUnixNano:      timeToNanos(dp.Timestamp),
+				Count:             dp.Count,
+				Sum:               &sum,
+				BucketCounts:      dp.BucketCounts,
+				ExplicitBounds:    dp.ExplicitBounds,
+				Exemplars:         exemplarsFromModel(dp.Exemplars),
+			})
+		}
+		out.Data = &metricspb.Metric_Histogram{Histogram: h}
+	case "summary":
+		s := &metricspb.Summary{}
+		for i := range m.DataPoints {
+			dp := &m.DataPoints[i]
+			point := &metricspb.SummaryDataPoint{
+				Attributes:        mapToKVs(dp.Attributes),
+				StartTimeUnixNano: timeToNanos(dp.StartTimestamp),
+				TimeUnixNano:      timeToNanos(dp.Timestamp),
+				Count:             dp.Count,
+				Sum:               dp.Sum,
+			}
+			for _, q := range dp.QuantileValues {
+				point.QuantileValues = append(point.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{Quantile: q.Quantile, Value: q.Value})
+			}
+			s.DataPoints = append(s.DataPoints, point)
+		}
+		out.Data = &metricspb.Metric_Summary{Summary: s}
+	default:
+		g := &metricspb.Gauge{}
+		for i := range m.DataPoints {
+			g.DataPoints = append(g.DataPoints, numberPointFromModel(&m.DataPoints[i]))
+		}
+		out.Data = &metricspb.Metric_Gauge{Gauge: g}
+	}
+	return out
+}
+
+func numberPointFromModel(dp *model.DataPoint) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:        mapToKVs(dp.Attributes),
+		StartTimeUnixNano: timeToNanos(dp.StartTimestamp),
+		TimeUnixNano:      timeToNanos(dp.Timestamp),
+		Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: dp.Value},
+		Exemplars:         exemplarsFromModel(dp.Exemplars),
+	}
+}
+
+func exemplarsFromModel(in []model.Exemplar) []*metricspb.Exemplar {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]*metricspb.Exemplar, 0, len(in))
+	for _, e := range in {
+		out = append(out, &metricspb.Exemplar{
+			FilteredAttributes: mapToKVs(e.FilteredAttributes),
+			TimeUnixNano:       timeToNanos(e.Timestamp),
+			Value:              &metricspb.Exemplar_AsDouble{AsDouble: e.Value},
+			TraceId:            idBytes(e.TraceID),
+			SpanId:             idBytes(e.SpanID),
+		})
+	}
+	return out
+}
+
+// nanosToTime converts Unix nanoseconds, treating 0 as unset.
+func nanosToTime(ns uint64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns)).UTC()
+}
+
+// timeToNanos converts a time to Unix nanoseconds, treating the zero time as 0.
+func timeToNanos(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
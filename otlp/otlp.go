@@ -0,0 +1,216 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/otlp/otlp.go
+
+// Package otlp converts OpenTelemetry OTLP export requests (metrics, logs and
+// traces, in protobuf or JSON encoding) into GoSight model payloads and back.
+// Resource attributes are mapped onto model.Meta following the OpenTelemetry
+// semantic conventions; anything without a dedicated Meta field is kept in
+// Meta.Labels.
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encoding is the wire encoding of an OTLP request.
+type Encoding string
+
+const (
+	// EncodingProtobuf is binary protobuf (application/x-protobuf).
+	EncodingProtobuf Encoding = "protobuf"
+
+	// EncodingJSON is OTLP/JSON (application/json). Trace and span IDs are
+	// hex strings rather than the base64 used by standard protobuf JSON.
+	EncodingJSON Encoding = "json"
+)
+
+// EncodingFromContentType picks the encoding from an HTTP Content-Type header,
+// defaulting to protobuf.
+func EncodingFromContentType(ct string) Encoding {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(ct)), "application/json") {
+		return EncodingJSON
+	}
+	return EncodingProtobuf
+}
+
+// ContentType returns the Content-Type header value for an encoding.
+func (e Encoding) ContentType() string {
+	if e == EncodingJSON {
+		return "application/json"
+	}
+	return "application/x-protobuf"
+}
+
+// DecodeMetricsRequest decodes an ExportMetricsServiceRequest.
+func DecodeMetricsRequest(data []byte, enc Encoding) (*colmetricspb.ExportMetricsServiceRequest, error) {
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	if err := unmarshal(data, enc, req); err != nil {
+		return nil, fmt.Errorf("otlp: decode metrics: %w", err)
+	}
+	return req, nil
+}
+
+// DecodeLogsRequest decodes an ExportLogsServiceRequest.
+func DecodeLogsRequest(data []byte, enc Encoding) (*collogspb.ExportLogsServiceRequest, error) {
+	req := &collogspb.ExportLogsServiceRequest{}
+	if err := unmarshal(data, enc, req); err != nil {
+		return nil, fmt.Errorf("otlp: decode logs: %w", err)
+	}
+	return req, nil
+}
+
+// DecodeTraceRequest decodes an ExportTraceServiceRequest.
+func DecodeTraceRequest(data []byte, enc Encoding) (*coltracepb.ExportTraceServiceRequest, error) {
+	req := &coltracepb.ExportTraceServiceRequest{}
+	if err := unmarshal(data, enc, req); err != nil {
+		return nil, fmt.Errorf("otlp: decode traces: %w", err)
+	}
+	return req, nil
+}
+
+// Encode serializes any OTLP request message in the given encoding.
+func Encode(msg proto.Message, enc Encoding) ([]byte, error) {
+	if enc != EncodingJSON {
+		return proto.Marshal(msg)
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return convertIDs(data, base64ToHex)
+}
+
+func unmarshal(data []byte, enc Encoding, msg proto.Message) error {
+	if enc != EncodingJSON {
+		return proto.Unmarshal(data, msg)
+	}
+	fixed, err := convertIDs(data, hexToBase64)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(fixed, msg)
+}
+
+// idKeys are the JSON fields that OTLP/JSON encodes as hex instead of base64.
+var idKeys = map[string]bool{
+	"traceId": true, "trace_id": true,
+	"spanId": true, "span_id": true,
+	"parentSpanId": true, "parent_span_id": true,
+}
+
+// convertIDs rewrites every trace/span ID string in a JSON document.
+func convertIDs(data []byte, conv func(string) (string, error)) ([]byte, error) {
+	// UseNumber keeps 64-bit timestamps sent as JSON numbers exact.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if err := walkIDs(doc, conv); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func walkIDs(v interface{}, conv func(string) (string, error)) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if s, ok := child.(string); ok && idKeys[k] {
+				out, err := conv(s)
+				if err != nil {
+					return fmt.Errorf("field %s: %w", k, err)
+				}
+				t[k] = out
+				continue
+			}
+			if err := walkIDs(child, conv); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range t {
+			if err := walkIDs(child, conv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hexToBase64(s string) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex id %q", s)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func base64ToHex(s string) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 id %q", s)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// idString renders a binary trace or span ID as lowercase hex, returning ""
+// for empty or all-zero IDs.
+func idString(b []byte) string {
+	for _, c := range b {
+		if c != 0 {
+			return hex.EncodeToString(b)
+		}
+	}
+	return ""
+}
+
+// idBytes parses a hex ID, returning nil if it is empty or malformed.
+func idBytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,335 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/otlp/resource.go
+
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// metaField binds a semantic-convention attribute key to a string field on
+// model.Meta.
+type metaField struct {
+	key string
+	get func(*model.Meta) string
+	set func(*model.Meta, string)
+}
+
+// semconv lists the resource attributes that have a dedicated Meta field.
+// Keys follow https://opentelemetry.io/docs/specs/semconv/resource/.
+var semconv = []metaField{
+	{"service.name", func(m *model.Meta) string { return m.ServiceName }, func(m *model.Meta, v string) { m.ServiceName = v }},
+	{"service.namespace", func(m *model.Meta) string { return m.ServiceNamespace }, func(m *model.Meta, v string) { m.ServiceNamespace = v }},
+	{"service.instance.id", func(m *model.Meta) string { return m.ServiceInstanceID }, func(m *model.Meta, v string) { m.ServiceInstanceID = v }},
+	{"service.version", func(m *model.Meta) string { return m.ServiceVersion }, func(m *model.Meta, v string) { m.ServiceVersion = v }},
+	{"telemetry.sdk.name", func(m *model.Meta) string { return m.TelemetrySDKName }, func(m *model.Meta, v string) { m.TelemetrySDKName = v }},
+	{"telemetry.sdk.version", func(m *model.Meta) string { return m.TelemetrySDKVersion }, func(m *model.Meta, v string) { m.TelemetrySDKVersion = v }},
+	{"telemetry.sdk.language", func(m *model.Meta) string { return m.TelemetrySDKLanguage }, func(m *model.Meta, v string) { m.TelemetrySDKLanguage = v }},
+	{"deployment.environment.name", func(m *model.Meta) string { return m.Environment }, func(m *model.Meta, v string) { m.Environment = v }},
+
+	{"host.name", func(m *model.Meta) string { return m.Hostname }, func(m *model.Meta, v string) { m.Hostname = v }},
+	{"host.id", func(m *model.Meta) string { return m.HostID }, func(m *model.Meta, v string) { m.HostID = v }},
+	{"host.arch", func(m *model.Meta) string { return m.Architecture }, func(m *model.Meta, v string) { m.Architecture = v }},
+	{"host.type", func(m *model.Meta) string { return m.InstanceType }, func(m *model.Meta, v string) { m.InstanceType = v }},
+	{"host.image.id", func(m *model.Meta) string { return m.ImageID }, func(m *model.Meta, v string) { m.ImageID = v }},
+	{"host.mac", func(m *model.Meta) string { return m.MACAddress }, func(m *model.Meta, v string) { m.MACAddress = v }},
+	{"host.ip", func(m *model.Meta) string { return m.IPAddress }, func(m *model.Meta, v string) { m.IPAddress = v }},
+	{"os.type", func(m *model.Meta) string { return m.OS }, func(m *model.Meta, v string) { m.OS = v }},
+	{"os.version", func(m *model.Meta) string { return m.OSVersion }, func(m *model.Meta, v string) { m.OSVersion = v }},
+	{"os.name", func(m *model.Meta) string { return m.Platform }, func(m *model.Meta, v string) { m.Platform = v }},
+
+	{"cloud.provider", func(m *model.Meta) string { return m.CloudProvider }, func(m *model.Meta, v string) { m.CloudProvider = v }},
+	{"cloud.region", func(m *model.Meta) string { return m.Region }, func(m *model.Meta, v string) { m.Region = v }},
+	{"cloud.availability_zone", func(m *model.Meta) string { return m.AvailabilityZone }, func(m *model.Meta, v string) { m.AvailabilityZone = v }},
+	{"cloud.account.id", func(m *model.Meta) string { return m.AccountID }, func(m *model.Meta, v string) { m.AccountID = v }},
+	{"cloud.resource_id", func(m *model.Meta) string { return m.ResourceID }, func(m *model.Meta, v string) { m.ResourceID = v }},
+
+	{"container.id", func(m *model.Meta) string { return m.ContainerID }, func(m *model.Meta, v string) { m.ContainerID = v }},
+	{"container.name", func(m *model.Meta) string { return m.ContainerName }, func(m *model.Meta, v string) { m.ContainerName = v }},
+	{"container.image.name", func(m *model.Meta) string { return m.ContainerImageName }, func(m *model.Meta, v string) { m.ContainerImageName = v }},
+	{"container.image.id", func(m *model.Meta) string { return m.ContainerImageID }, func(m *model.Meta, v string) { m.ContainerImageID = v }},
+
+	{"k8s.pod.name", func(m *model.Meta) string { return m.PodName }, func(m *model.Meta, v string) { m.PodName = v }},
+	{"k8s.pod.uid", func(m *model.Meta) string { return m.PodUID }, func(m *model.Meta, v string) { m.PodUID = v }},
+	{"k8s.namespace.name", func(m *model.Meta) string { return m.Namespace }, func(m *model.Meta, v string) { m.Namespace = v }},
+	{"k8s.cluster.name", func(m *model.Meta) string { return m.ClusterName }, func(m *model.Meta, v string) { m.ClusterName = v }},
+	{"k8s.cluster.uid", func(m *model.Meta) string { return m.ClusterUID }, func(m *model.Meta, v string) { m.ClusterUID = v }},
+	{"k8s.node.name", func(m *model.Meta) string { return m.NodeName }, func(m *model.Meta, v string) { m.NodeName = v }},
+	{"k8s.deployment.name", func(m *model.Meta) string { return m.DeploymentName }, func(m *model.Meta, v string) { m.DeploymentName = v }},
+
+	{"process.executable.name", func(m *model.Meta) string { return m.ProcessName }, func(m *model.Meta, v string) { m.ProcessName = v }},
+	{"process.executable.path", func(m *model.Meta) string { return m.Executable }, func(m *model.Meta, v string) { m.Executable = v }},
+	{"process.runtime.name", func(m *model.Meta) string { return m.RuntimeName }, func(m *model.Meta, v string) { m.RuntimeName = v }},
+	{"process.runtime.version", func(m *model.Meta) string { return m.RuntimeVersion }, func(m *model.Meta, v string) { m.RuntimeVersion = v }},
+	{"process.owner", func(m *model.Meta) string { return m.User }, func(m *model.Meta, v string) { m.User = v }},
+}
+
+// semconvAliases are older or alternative keys mapped to the canonical ones.
+var semconvAliases = map[string]string{
+	"deployment.environment": "deployment.environment.name",
+}
+
+var semconvIndex = func() map[string]metaField {
+	idx := make(map[string]metaField, len(semconv))
+	for _, f := range semconv {
+		idx[f.key] = f
+	}
+	return idx
+}()
+
+// ResourceToMeta maps OTLP resource and scope information onto a model.Meta.
+// Attributes without a dedicated field are stored in Meta.Labels.
+func ResourceToMeta(res *resourcepb.Resource, scope *commonpb.InstrumentationScope) *model.Meta {
+	meta := &model.Meta{Labels: map[string]string{}}
+	if res != nil {
+		for _, kv := range res.GetAttributes() {
+			key := kv.GetKey()
+			if alias, ok := semconvAliases[key]; ok {
+				key = alias
+			}
+			if key == "process.pid" {
+				if pid, err := strconv.Atoi(anyToString(kv.GetValue())); err == nil {
+					meta.ProcessID = pid
+				}
+				continue
+			}
+			if key == "host.ip" {
+				// host.ip is an array; keep the first address.
+				if arr := kv.GetValue().GetArrayValue(); arr != nil && len(arr.GetValues()) > 0 {
+					meta.IPAddress = anyToString(arr.GetValues()[0])
+					continue
+				}
+			}
+			if f, ok := semconvIndex[key]; ok {
+				f.set(meta, anyToString(kv.GetValue()))
+				continue
+			}
+			meta.Labels[kv.GetKey()] = anyToString(kv.GetValue())
+		}
+	}
+	if scope != nil {
+		meta.InstrumentationLibrary = scope.GetName()
+		meta.InstrumentationLibVersion = scope.GetVersion()
+	}
+	return meta
+}
+
+// MetaToResource builds an OTLP resource from a model.Meta. Meta.Labels are
+// emitted as additional attributes.
+func MetaToResource(meta *model.Meta) *resourcepb.Resource {
+	res := &resourcepb.Resource{}
+	if meta == nil {
+		return res
+	}
+	for _, f := range semconv {
+		if v := f.get(meta); v != "" {
+			res.Attributes = append(res.Attributes, stringKV(f.key, v))
+		}
+	}
+	if meta.ServiceName == "" && meta.Service != "" {
+		res.Attributes = append(res.Attributes, stringKV("service.name", meta.Service))
+	}
+	if meta.ProcessID != 0 {
+		res.Attributes = append(res.Attributes, &commonpb.KeyValue{
+			Key:   "process.pid",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(meta.ProcessID)}},
+		})
+	}
+	res.Attributes = append(res.Attributes, mapToKVs(meta.Labels)...)
+	return res
+}
+
+// MetaToScope returns the instrumentation scope recorded in meta, if any.
+func MetaToScope(meta *model.Meta) *commonpb.InstrumentationScope {
+	if meta == nil || meta.InstrumentationLibrary == "" {
+		return nil
+	}
+	return &commonpb.InstrumentationScope{Name: meta.InstrumentationLibrary, Version: meta.InstrumentationLibVersion}
+}
+
+// anyToString renders an AnyValue as a string. Arrays and maps are rendered
+// as JSON and bytes as base64.
+func anyToString(v *commonpb.AnyValue) string {
+	switch t := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return t.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(t.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(t.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(t.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(t.BytesValue)
+	case *commonpb.AnyValue_ArrayValue, *commonpb.AnyValue_KvlistValue:
+		b, _ := json.Marshal(anyToInterface(v))
+		return string(b)
+	}
+	return ""
+}
+
+// anyToInterface converts an AnyValue into plain Go values.
+func anyToInterface(v *commonpb.AnyValue) interface{} {
+	switch t := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return t.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return t.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return t.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return t.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return t.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		out := make([]interface{}, 0, len(t.ArrayValue.GetValues()))
+		for _, e := range t.ArrayValue.GetValues() {
+			out = append(out, anyToInterface(e))
+		}
+		return out
+	case *commonpb.AnyValue_KvlistValue:
+		out := make(map[string]interface{}, len(t.KvlistValue.GetValues()))
+		for _, kv := range t.KvlistValue.GetValues() {
+			out[kv.GetKey()] = anyToInterface(kv.GetValue())
+		}
+		return out
+	}
+	return nil
+}
+
+// interfaceToAny is the inverse of anyToInterface.
+func interfaceToAny(v interface{}) *commonpb.AnyValue {
+	switch t := v.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: t}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: t}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(t)}}
+	case int32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(t)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: t}}
+	case uint32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(t)}}
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(t)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: t}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: t}}
+	case []interface{}:
+		arr := &commonpb.ArrayValue{}
+		for _, e := range t {
+			arr.Values = append(arr.Values, interfaceToAny(e))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: arr}}
+	case map[string]interface{}:
+		kvs := &commonpb.KeyValueList{}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kvs.Values = append(kvs.Values, &commonpb.KeyValue{Key: k, Value: interfaceToAny(t[k])})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: kvs}}
+	case map[string]string:
+		m := make(map[string]interface{}, len(t))
+		for k, s := range t {
+			m[k] = s
+		}
+		return interfaceToAny(m)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return &commonpb.AnyValue{}
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: string(b)}}
+}
+
+// kvsToMap flattens attributes to strings.
+func kvsToMap(kvs []*commonpb.KeyValue) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		out[kv.GetKey()] = anyToString(kv.GetValue())
+	}
+	return out
+}
+
+// kvsToInterfaceMap keeps attribute value types.
+func kvsToInterfaceMap(kvs []*commonpb.KeyValue) map[string]interface{} {
+	if len(kvs) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		out[kv.GetKey()] = anyToInterface(kv.GetValue())
+	}
+	return out
+}
+
+// mapToKVs converts string attributes into sorted OTLP key/values.
+func mapToKVs(m map[string]string) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, stringKV(k, m[k]))
+	}
+	return out
+}
+
+// interfaceMapToKVs converts typed attributes into sorted OTLP key/values.
+func interfaceMapToKVs(m map[string]interface{}) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, &commonpb.KeyValue{Key: k, Value: interfaceToAny(m[k])})
+	}
+	return out
+}
+
+func stringKV(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: k, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}}
+}
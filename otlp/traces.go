@@ -0,0 +1,189 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/otlp/traces.go
+
+package otlp
+
+import (
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// SpanKindAttribute is the span attribute that carries the OTLP span kind
+// ("server", "client", "producer", "consumer" or "internal").
+const SpanKindAttribute = "span.kind"
+
+// TracesToModel converts an OTLP trace export into one TracePayload per
+// resource/scope pair.
+func TracesToModel(req *coltracepb.ExportTraceServiceRequest) []model.TracePayload {
+	var out []model.TracePayload
+	for _, rs := range req.GetResourceSpans() {
+		resAttrs := kvsToMap(rs.GetResource().GetAttributes())
+		for _, ss := range rs.GetScopeSpans() {
+			meta := ResourceToMeta(rs.GetResource(), ss.GetScope())
+			payload := model.TracePayload{Meta: meta}
+			for _, s := range ss.GetSpans() {
+				payload.Traces = append(payload.Traces, spanToModel(s, meta, resAttrs))
+			}
+			out = append(out, payload)
+		}
+	}
+	return out
+}
+
+func spanToModel(s *tracepb.Span, meta *model.Meta, resAttrs map[string]string) model.TraceSpan {
+	span := model.TraceSpan{
+		TraceID:       idString(s.GetTraceId()),
+		SpanID:        idString(s.GetSpanId()),
+		ParentSpanID:  idString(s.GetParentSpanId()),
+		Name:          s.GetName(),
+		ServiceName:   meta.ServiceName,
+		EndpointID:    meta.EndpointID,
+		AgentID:       meta.AgentID,
+		HostID:        meta.HostID,
+		StartTime:     nanosToTime(s.GetStartTimeUnixNano()),
+		EndTime:       nanosToTime(s.GetEndTimeUnixNano()),
+		StatusCode:    statusCode(s.GetStatus().GetCode()),
+		StatusMessage: s.GetStatus().GetMessage(),
+		Attributes:    kvsToMap(s.GetAttributes()),
+		ResourceAttrs: resAttrs,
+		Meta:          meta,
+	}
+	if !span.StartTime.IsZero() && !span.EndTime.IsZero() {
+		span.DurationMs = float64(span.EndTime.Sub(span.StartTime).Nanoseconds()) / 1e6
+	}
+	if kind := spanKind(s.GetKind()); kind != "" {
+		if span.Attributes == nil {
+			span.Attributes = map[string]string{}
+		}
+		span.Attributes[SpanKindAttribute] = kind
+	}
+	for _, ev := range s.GetEvents() {
+		span.Events = append(span.Events, model.SpanEvent{
+			Name:       ev.GetName(),
+			Timestamp:  nanosToTime(ev.GetTimeUnixNano()),
+			Attributes: kvsToMap(ev.GetAttributes()),
+		})
+	}
+	return span
+}
+
+func statusCode(c tracepb.Status_StatusCode) string {
+	switch c {
+	case tracepb.Status_STATUS_CODE_OK:
+		return "OK"
+	case tracepb.Status_STATUS_CODE_ERROR:
+		return "ERROR"
+	}
+	return "UNSET"
+}
+
+func spanKind(k tracepb.Span_SpanKind) string {
+	switch k {
+	case tracepb.Span_SPAN_KIND_SERVER:
+		return "server"
+	case tracepb.Span_SPAN_KIND_CLIENT:
+		return "client"
+	case tracepb.Span_SPAN_KIND_PRODUCER:
+		return "producer"
+	case tracepb.Span_SPAN_KIND_CONSUMER:
+		return "consumer"
+	case tracepb.Span_SPAN_KIND_INTERNAL:
+		return "internal"
+	}
+	return ""
+}
+
+func spanKindFromString(s string) tracepb.Span_SpanKind {
+	switch strings.ToLower(s) {
+	case "server":
+		return tracepb.Span_SPAN_KIND_SERVER
+	case "client":
+		return tracepb.Span_SPAN_KIND_CLIENT
+	case "producer":
+		return tracepb.Span_SPAN_KIND_PRODUCER
+	case "consumer":
+		return tracepb.Span_SPAN_KIND_CONSUMER
+	case "internal":
+		return tracepb.Span_SPAN_KIND_INTERNAL
+	}
+	return tracepb.Span_SPAN_KIND_UNSPECIFIED
+}
+
+// TracesFromModel converts GoSight trace payloads into an OTLP export
+// request, one ResourceSpans per payload.
+func TracesFromModel(payloads []model.TracePayload) *coltracepb.ExportTraceServiceRequest {
+	req := &coltracepb.ExportTraceServiceRequest{}
+	for _, p := range payloads {
+		scope := &tracepb.ScopeSpans{Scope: MetaToScope(p.Meta)}
+		for i := range p.Traces {
+			scope.Spans = append(scope.Spans, spanFromModel(&p.Traces[i]))
+		}
+		res := MetaToResource(p.Meta)
+		if p.Meta == nil && len(p.Traces) > 0 {
+			res.Attributes = mapToKVs(p.Traces[0].ResourceAttrs)
+		}
+		req.ResourceSpans = append(req.ResourceSpans, &tracepb.ResourceSpans{
+			Resource:   res,
+			ScopeSpans: []*tracepb.ScopeSpans{scope},
+		})
+	}
+	return req
+}
+
+func spanFromModel(s *model.TraceSpan) *tracepb.Span {
+	attrs := make(map[string]string, len(s.Attributes))
+	for k, v := range s.Attributes {
+		if k != SpanKindAttribute {
+			attrs[k] = v
+		}
+	}
+
+	out := &tracepb.Span{
+		TraceId:           idBytes(s.TraceID),
+		SpanId:            idBytes(s.SpanID),
+		ParentSpanId:      idBytes(s.ParentSpanID),
+		Name:              s.Name,
+		Kind:              spanKindFromString(s.Attributes[SpanKindAttribute]),
+		StartTimeUnixNano: timeToNanos(s.StartTime),
+		EndTimeUnixNano:   timeToNanos(s.EndTime),
+		Attributes:        mapToKVs(attrs),
+		Status:            &tracepb.Status{Message: s.StatusMessage},
+	}
+	switch strings.ToUpper(s.StatusCode) {
+	case "OK":
+		out.Status.Code = tracepb.Status_STATUS_CODE_OK
+	case "ERROR":
+		out.Status.Code = tracepb.Status_STATUS_CODE_ERROR
+	}
+	for _, ev := range s.Events {
+		out.Events = append(out.Events, &tracepb.Span_Event{
+			Name:         ev.Name,
+			TimeUnixNano: timeToNanos(ev.Timestamp),
+			Attributes:   mapToKVs(ev.Attributes),
+		})
+	}
+	return out
+}
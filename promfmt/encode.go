@@ -0,0 +1,362 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/promfmt/encode.go
+
+package promfmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+// EncodeOptions controls how metrics are written.
+type EncodeOptions struct {
+	Format Format
+
+	// WithNamespace prefixes metric names with Namespace and SubNamespace,
+	// e.g. "System" + "CPU" + "usage_percent" -> "system_cpu_usage_percent".
+	WithNamespace bool
+
+	// WithMetaLabels adds utils.ExtractStandardLabels(metric.Meta) to every
+	// sample. Data point attributes win on conflict.
+	WithMetaLabels bool
+
+	// OmitTimestamps leaves sample timestamps out, letting the scraper stamp them.
+	OmitTimestamps bool
+}
+
+// Encode writes metrics in the requested exposition format. Metrics that
+// resolve to the same name are grouped under one HELP/TYPE header, and
+// families are written in name order.
+func Encode(w io.Writer, metrics []model.Metric, opts EncodeOptions) error {
+	if opts.Format == "" {
+		opts.Format = FormatText
+	}
+
+	type famOut struct {
+		name    string
+		typ     string
+		help    string
+		unit    string
+		metrics []*model.Metric
+	}
+	families := make(map[string]*famOut)
+	for i := range metrics {
+		m := &metrics[i]
+		name := MetricName(m, opts.WithNamespace)
+		typ := promType(m)
+		if f, ok := families[name]; ok {
+			if f.typ != typ {
+				return fmt.Errorf("promfmt: metric %q has conflicting types %s and %s", name, f.typ, typ)
+			}
+			f.metrics = append(f.metrics, m)
+			continue
+		}
+		families[name] = &famOut{name: name, typ: typ, help: m.Description, unit: m.Unit, metrics: []*model.Metric{m}}
+	}
+
+	names := make([]string, 0, len(families))
+	for n := range families {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: bw, opts: opts}
+	for _, n := range names {
+		f := families[n]
+		header := f.name
+		if opts.Format == FormatOpenMetrics && f.typ == "counter" {
+			// OpenMetrics names the counter family without the _total suffix.
+			header = strings.TrimSuffix(header, "_total")
+		}
+		if f.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", header, escapeHelp(f.help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", header, f.typ)
+		if opts.Format == FormatOpenMetrics && f.unit != "" && strings.HasSuffix(header, "_"+sanitizeName(f.unit)) {
+			fmt.Fprintf(bw, "# UNIT %s %s\n", header, sanitizeName(f.unit))
+		}
+		for _, m := range f.metrics {
+			enc.metric(f.name, f.typ, m)
+		}
+	}
+	if opts.Format == FormatOpenMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+// MetricName returns the exposition name for a metric, sanitized to the
+// Prometheus character set.
+func MetricName(m *model.Metric, withNamespace bool) string {
+	parts := []string{}
+	if withNamespace {
+		if m.Namespace != "" {
+			parts = append(parts, strings.ToLower(m.Namespace))
+		}
+		if m.SubNamespace != "" {
+			parts = append(parts, strings.ToLower(m.SubNamespace))
+		}
+	}
+	parts = append(parts, m.Name)
+	return sanitizeName(strings.Join(parts, "_"))
+}
+
+// promType maps a model DataType to a Prometheus type. Delta and
+// non-monotonic sums cannot be represented as counters and are exposed as
+// gauges.
+func promType(m *model.Metric) string {
+	switch strings.ToLower(m.DataType) {
+	case "sum", "counter":
+		if m.NonMonotonic || strings.EqualFold(m.AggregationTemporality, "delta") {
+			return "gauge"
+		}
+		return "counter"
+	case "histogram":
+		return "histogram"
+	case "summary":
+		return "summary"
+	default:
+		return "gauge"
+	}
+}
+
+type encoder struct {
+	w    *bufio.Writer
+	opts EncodeOptions
+}
+
+func (e *encoder) metric(name, typ string, m *model.Metric) {
+	var base map[string]string
+	if e.opts.WithMetaLabels && m.Meta != nil {
+		base = utils.ExtractStandardLabels(m.Meta)
+	}
+
+	om := e.opts.Format == FormatOpenMetrics
+	for i := range m.DataPoints {
+		dp := &m.DataPoints[i]
+		labels := utils.MergeMaps(base, dp.Attributes)
+		ts := e.timestamp(dp)
+
+		switch typ {
+		case "counter":
+			sample := name
+			if om && !strings.HasSuffix(name, "_total") {
+				sample += "_total"
+			}
+			e.sample(sample, labels, "", "", dp.Value, ts, firstExemplar(dp))
+			if om && !dp.StartTimestamp.IsZero() {
+				e.sample(strings.TrimSuffix(name, "_total")+"_created", labels, "", "", unixSeconds(dp), ts, nil)
+			}
+		case "histogram":
+			var cumulative uint64
+			for b, c := range dp.BucketCounts {
+				cumulative += c
+				le := math.Inf(1)
+				if b < len(dp.ExplicitBounds) {
+					le = dp.ExplicitBounds[b]
+				}
+				e.sample(name+"_bucket", labels, "le", formatFloat(le), float64(cumulative), ts, exemplarFor(dp, le, b))
+			}
+			if len(dp.BucketCounts) == 0 {
+				e.sample(name+"_bucket", labels, "le", "+Inf", float64(dp.Count), ts, nil)
+			}
+			e.sample(name+"_sum", labels, "", "", dp.Sum, ts, nil)
+			e.sample(name+"_count", labels, "", "", float64(dp.Count), ts, nil)
+		case "summary":
+			for _, q := range dp.QuantileValues {
+				e.sample(name, labels, "quantile", formatFloat(q.Quantile), q.Value, ts, nil)
+			}
+			e.sample(name+"_sum", labels, "", "", dp.Sum, ts, nil)
+			e.sample(name+"_count", labels, "", "", float64(dp.Count), ts, nil)
+		default:
+			e.sample(name, labels, "", "", dp.Value, ts, nil)
+		}
+	}
+}
+
+// sample writes one line. extraName/extraValue add the le or quantile label.
+func (e *encoder) sample(name string, labels map[string]string, extraName, extraValue string, value float64, ts string, ex *model.Exemplar) {
+	w := e.w
+	w.WriteString(name)
+	writeLabels(w, labels, extraName, extraValue)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	if ts != "" {
+		w.WriteByte(' ')
+		w.WriteString(ts)
+	}
+	if ex != nil && e.opts.Format == FormatOpenMetrics {
+		exLabels := map[string]string{}
+		for k, v := range ex.FilteredAttributes {
+			exLabels[k] = v
+		}
+		if ex.TraceID != "" {
+			exLabels["trace_id"] = ex.TraceID
+		}
+		if ex.SpanID != "" {
+			exLabels["span_id"] = ex.SpanID
+		}
+		w.WriteString(" # ")
+		writeLabelSet(w, exLabels, "", "")
+		w.WriteByte(' ')
+		w.WriteString(formatFloat(ex.Value))
+		if !ex.Timestamp.IsZero() {
+			w.WriteByte(' ')
+			w.WriteString(strconv.FormatFloat(float64(ex.Timestamp.UnixNano())/1e9, 'f', -1, 64))
+		}
+	}
+	w.WriteByte('\n')
+}
+
+func (e *encoder) timestamp(dp *model.DataPoint) string {
+	if e.opts.OmitTimestamps || dp.Timestamp.IsZero() {
+		return ""
+	}
+	if e.opts.Format == FormatOpenMetrics {
+		return strconv.FormatFloat(float64(dp.Timestamp.UnixNano())/1e9, 'f', -1, 64)
+	}
+	return strconv.FormatInt(dp.Timestamp.UnixMilli(), 10)
+}
+
+func unixSeconds(dp *model.DataPoint) float64 {
+	return float64(dp.StartTimestamp.UnixNano()) / 1e9
+}
+
+// firstExemplar returns the first exemplar of a point, if any.
+func firstExemplar(dp *model.DataPoint) *model.Exemplar {
+	if len(dp.Exemplars) == 0 {
+		return nil
+	}
+	return &dp.Exemplars[0]
+}
+
+// exemplarFor picks an exemplar whose value falls in bucket b (upper bound le).
+func exemplarFor(dp *model.DataPoint, le float64, b int) *model.Exemplar {
+	lower := math.Inf(-1)
+	if b > 0 && b-1 < len(dp.ExplicitBounds) {
+		lower = dp.ExplicitBounds[b-1]
+	}
+	for i := range dp.Exemplars {
+		v := dp.Exemplars[i].Value
+		if v > lower && v <= le {
+			return &dp.Exemplars[i]
+		}
+	}
+	return nil
+}
+
+func writeLabels(w *bufio.Writer, labels map[string]string, extraName, extraValue string) {
+	if len(labels) == 0 && extraName == "" {
+		return
+	}
+	writeLabelSet(w, labels, extraName, extraValue)
+}
+
+func writeLabelSet(w *bufio.Writer, labels map[string]string, extraName, extraValue string) {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if k == extraName {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w.WriteByte('{')
+	first := true
+	for _, k := range keys {
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		w.WriteString(sanitizeLabel(k))
+		w.WriteString(`="`)
+		w.WriteString(escapeLabelValue(labels[k]))
+		w.WriteByte('"')
+	}
+	if extraName != "" {
+		if !first {
+			w.WriteByte(',')
+		}
+		w.WriteString(extraName)
+		w.WriteString(`="`)
+		w.WriteString(extraValue)
+		w.WriteByte('"')
+	}
+	w.WriteByte('}')
+}
+
+// formatFloat renders a float the way Prometheus expects.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sanitizeName maps a name onto [a-zA-Z_:][a-zA-Z0-9_:]*.
+func sanitizeName(s string) string {
+	return sanitize(s, true)
+}
+
+// sanitizeLabel maps a label name onto [a-zA-Z_][a-zA-Z0-9_]*.
+func sanitizeLabel(s string) string {
+	return sanitize(s, false)
+}
+
+func sanitize(s string, allowColon bool) string {
+	if s == "" {
+		return "_"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		ok := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(i > 0 && c >= '0' && c <= '9') || (allowColon && c == ':')
+		if !ok {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,606 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/promfmt/parse.go
+
+// Package promfmt parses and encodes the Prometheus text exposition format
+// (version 0.0.4) and OpenMetrics 1.0, converting between them and
+// model.Metric. Counters map to "sum" metrics with cumulative temporality,
+// histograms and summaries fill the matching DataPoint fields.
+package promfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Format identifies an exposition format.
+type Format string
+
+const (
+	// FormatText is the Prometheus text format, version 0.0.4.
+	FormatText Format = "text"
+
+	// FormatOpenMetrics is OpenMetrics 1.0.0.
+	FormatOpenMetrics Format = "openmetrics"
+)
+
+// Content types for each format.
+const (
+	ContentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Source is set on every parsed model.Metric.
+const Source = "prometheus"
+
+// FormatFromContentType picks the format from an HTTP Content-Type header,
+// defaulting to FormatText.
+func FormatFromContentType(ct string) Format {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(ct)), "application/openmetrics-text") {
+		return FormatOpenMetrics
+	}
+	return FormatText
+}
+
+// ContentType returns the Content-Type header value for a format.
+func (f Format) ContentType() string {
+	if f == FormatOpenMetrics {
+		return ContentTypeOpenMetrics
+	}
+	return ContentTypeText
+}
+
+// family collects the samples of one metric family while parsing.
+type family struct {
+	name   string
+	typ    string
+	help   string
+	unit   string
+	points map[string]*pointBuilder
+	order  []string
+}
+
+// pointBuilder accumulates the samples that make up a single DataPoint.
+type pointBuilder struct {
+	labels    map[string]string
+	value     float64
+	ts        time.Time
+	created   time.Time
+	buckets   map[float64]float64 // le -> cumulative count
+	quantiles map[float64]float64
+	sum       float64
+	count     float64
+	hasCount  bool
+	exemplars []model.Exemplar
+}
+
+// Parse reads an exposition in the given format. Samples without a timestamp
+// are stamped with now.
+func Parse(r io.Reader, format Format, now time.Time) ([]model.Metric, error) {
+	p := &parser{format: format, now: now, families: make(map[string]*family)}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		if p.eof {
+			if strings.TrimSpace(sc.Text()) != "" {
+				return nil, fmt.Errorf("promfmt: line %d: content after # EOF", lineNo)
+			}
+			continue
+		}
+		if err := p.line(sc.Text()); err != nil {
+			return nil, fmt.Errorf("promfmt: line %d: %w", lineNo, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return p.metrics(), nil
+}
+
+// ParseBytes is a convenience wrapper around Parse.
+func ParseBytes(data []byte, format Format, now time.Time) ([]model.Metric, error) {
+	return Parse(bytes.NewReader(data), format, now)
+}
+
+type parser struct {
+	format   Format
+	now      time.Time
+	families map[string]*family
+	order    []string
+	eof      bool
+}
+
+func (p *parser) family(name string) *family {
+	f := p.families[name]
+	if f == nil {
+		f = &family{name: name, typ: "untyped", points: make(map[string]*pointBuilder)}
+		p.families[name] = f
+		p.order = append(p.order, name)
+	}
+	return f
+}
+
+func (p *parser) line(line string) error {
+	line = strings.TrimRight(line, "\r")
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return nil
+	}
+	if strings.HasPrefix(trimmed, "#") {
+		return p.comment(trimmed)
+	}
+	return p.sample(trimmed)
+}
+
+func (p *parser) comment(line string) error {
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "#")), " ", 3)
+	switch fields[0] {
+	case "EOF":
+		p.eof = true
+	case "HELP":
+		if len(fields) < 2 {
+			return fmt.Errorf("malformed HELP line")
+		}
+		help := ""
+		if len(fields) == 3 {
+			help = unescapeHelp(fields[2])
+		}
+		p.family(fields[1]).help = help
+	case "TYPE":
+		if len(fields) < 3 {
+			return fmt.Errorf("malformed TYPE line")
+		}
+		typ := strings.ToLower(strings.TrimSpace(fields[2]))
+		switch typ {
+		case "counter", "gauge", "histogram", "summary", "untyped", "unknown", "gaugehistogram", "stateset", "info":
+		default:
+			return fmt.Errorf("unknown metric type %q", typ)
+		}
+		p.family(fields[1]).typ = typ
+	case "UNIT":
+		if len(fields) == 3 {
+			p.family(fields[1]).unit = strings.TrimSpace(fields[2])
+		}
+	}
+	// Any other comment is ignored.
+	return nil
+}
+
+// sample parses `name{labels} value [timestamp] [# {exemplar} value [ts]]`.
+func (p *parser) sample(line string) error {
+	var exemplarPart string
+	if i := findExemplar(line); i >= 0 {
+		exemplarPart = strings.TrimSpace(line[i+1:])
+		line = strings.TrimSpace(line[:i])
+	}
+
+	name, rest := splitName(line)
+	if name == "" {
+		return fmt.Errorf("missing metric name")
+	}
+
+	labels := map[string]string{}
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		labels, rest, err = parseLabels(rest)
+		if err != nil {
+			return err
+		}
+	}
+
+	parts := strings.Fields(rest)
+	if len(parts) == 0 || len(parts) > 2 {
+		return fmt.Errorf("expected value and optional timestamp, got %q", rest)
+	}
+	value, err := parseFloat(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid value %q: %w", parts[0], err)
+	}
+	ts := p.now
+	if len(parts) == 2 {
+		if ts, err = p.parseTimestamp(parts[1]); err != nil {
+			return err
+		}
+	}
+
+	fam, suffix := p.resolveFamily(name)
+	pb := fam.point(labels)
+	if ts.After(pb.ts) {
+		pb.ts = ts
+	}
+
+	switch suffix {
+	case "_bucket":
+		le, err := parseFloat(labels["le"])
+		if err != nil {
+			return fmt.Errorf("invalid le label %q", labels["le"])
+		}
+		if pb.buckets == nil {
+			pb.buckets = make(map[float64]float64)
+		}
+		pb.buckets[le] = value
+	case "_sum":
+		pb.sum = value
+	case "_count":
+		pb.count, pb.hasCount = value, true
+	case "_created":
+		pb.created = floatTime(value)
+	case "_total", "":
+		if q, ok := labels["quantile"]; ok && fam.typ == "summary" {
+			qv, err := parseFloat(q)
+			if err != nil {
+				return fmt.Errorf("invalid quantile label %q", q)
+			}
+			if pb.quantiles == nil {
+				pb.quantiles = make(map[float64]float64)
+			}
+			pb.quantiles[qv] = value
+		} else {
+			pb.value = value
+		}
+	}
+
+	if exemplarPart != "" {
+		ex, err := p.parseExemplar(exemplarPart)
+		if err != nil {
+			return err
+		}
+		pb.exemplars = append(pb.exemplars, ex)
+	}
+	return nil
+}
+
+// resolveFamily maps a sample name to its family and the suffix it carries.
+func (p *parser) resolveFamily(name string) (*family, string) {
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_created", "_total"} {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		base := strings.TrimSuffix(name, suffix)
+		f, ok := p.families[base]
+		if !ok {
+			continue
+		}
+		switch f.typ {
+		case "histogram", "gaugehistogram":
+			if suffix != "_total" {
+				return f, suffix
+			}
+		case "summary":
+			if suffix == "_sum" || suffix == "_count" || suffix == "_created" {
+				return f, suffix
+			}
+		case "counter":
+			if suffix == "_total" || suffix == "_created" {
+				return f, suffix
+			}
+		}
+	}
+	return p.family(name), ""
+}
+
+// point returns the builder for a label set, ignoring le/quantile.
+func (f *family) point(labels map[string]string) *pointBuilder {
+	clean := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k == "le" || k == "quantile" {
+			continue
+		}
+		clean[k] = v
+	}
+	key := labelKey(clean)
+	pb := f.points[key]
+	if pb == nil {
+		pb = &pointBuilder{labels: clean}
+		f.points[key] = pb
+		f.order = append(f.order, key)
+	}
+	return pb
+}
+
+func (p *parser) parseTimestamp(s string) (time.Time, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	if p.format == FormatOpenMetrics {
+		return floatTime(v), nil
+	}
+	return time.UnixMilli(int64(v)).UTC(), nil
+}
+
+// parseExemplar parses `{labels} value [timestamp]` (OpenMetrics).
+func (p *parser) parseExemplar(s string) (model.Exemplar, error) {
+	var ex model.Exemplar
+	if !strings.HasPrefix(s, "{") {
+		return ex, fmt.Errorf("malformed exemplar %q", s)
+	}
+	labels, rest, err := parseLabels(s)
+	if err != nil {
+		return ex, fmt.Errorf("exemplar: %w", err)
+	}
+	parts := strings.Fields(rest)
+	if len(parts) == 0 {
+		return ex, fmt.Errorf("exemplar missing value")
+	}
+	if ex.Value, err = parseFloat(parts[0]); err != nil {
+		return ex, fmt.Errorf("exemplar value %q: %w", parts[0], err)
+	}
+	if len(parts) > 1 {
+		v, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return ex, fmt.Errorf("exemplar timestamp %q", parts[1])
+		}
+		ex.Timestamp = floatTime(v)
+	}
+	ex.TraceID = labels["trace_id"]
+	ex.SpanID = labels["span_id"]
+	delete(labels, "trace_id")
+	delete(labels, "span_id")
+	if len(labels) > 0 {
+		ex.FilteredAttributes = labels
+	}
+	return ex, nil
+}
+
+// metrics converts the parsed families into model metrics, preserving input order.
+func (p *parser) metrics() []model.Metric {
+	out := make([]model.Metric, 0, len(p.order))
+	for _, name := range p.order {
+		f := p.families[name]
+		if len(f.points) == 0 {
+			continue
+		}
+		m := model.Metric{
+			Name:        f.name,
+			Description: f.help,
+			Unit:        f.unit,
+			Source:      Source,
+		}
+		switch f.typ {
+		case "counter":
+			m.DataType = "sum"
+			m.AggregationTemporality = "cumulative"
+		case "histogram", "gaugehistogram":
+			m.DataType = "histogram"
+			m.AggregationTemporality = "cumulative"
+		case "summary":
+			m.DataType = "summary"
+		default:
+			m.DataType = "gauge"
+		}
+
+		for _, key := range f.order {
+			m.DataPoints = append(m.DataPoints, f.points[key].build(m.DataType))
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func (pb *pointBuilder) build(dataType string) model.DataPoint {
+	dp := model.DataPoint{
+		Attributes:     pb.labels,
+		Timestamp:      pb.ts,
+		StartTimestamp: pb.created,
+		Exemplars:      pb.exemplars,
+	}
+	switch dataType {
+	case "histogram":
+		bounds := make([]float64, 0, len(pb.buckets))
+		for le := range pb.buckets {
+			if !math.IsInf(le, 1) {
+				bounds = append(bounds, le)
+			}
+		}
+		sort.Float64s(bounds)
+
+		counts := make([]uint64, len(bounds)+1)
+		var prev float64
+		for i, le := range bounds {
+			c := pb.buckets[le]
+			counts[i] = uint64(math.Max(c-prev, 0))
+			prev = c
+		}
+		total := prev
+		if inf, ok := pb.buckets[math.Inf(1)]; ok {
+			total = inf
+		} else if pb.hasCount {
+			total = pb.count
+		}
+		counts[len(bounds)] = uint64(math.Max(total-prev, 0))
+
+		dp.ExplicitBounds = bounds
+		dp.BucketCounts = counts
+		dp.Sum = pb.sum
+		dp.Count = uint64(total)
+		if pb.hasCount {
+			dp.Count = uint64(pb.count)
+		}
+	case "summary":
+		qs := make([]float64, 0, len(pb.quantiles))
+		for q := range pb.quantiles {
+			qs = append(qs, q)
+		}
+		sort.Float64s(qs)
+		for _, q := range qs {
+			dp.QuantileValues = append(dp.QuantileValues, model.QuantileValue{Quantile: q, Value: pb.quantiles[q]})
+		}
+		dp.Sum = pb.sum
+		dp.Count = uint64(pb.count)
+	default:
+		dp.Value = pb.value
+	}
+	return dp
+}
+
+// splitName splits the metric name from the rest of a sample line.
+func splitName(line string) (string, string) {
+	for i, r := range line {
+		if r == '{' || r == ' ' || r == '\t' {
+			return line[:i], strings.TrimLeft(line[i:], " \t")
+		}
+	}
+	return line, ""
+}
+
+// parseLabels parses a `{k="v",...}` block and returns the remainder.
+func parseLabels(s string) (map[string]string, string, error) {
+	labels := map[string]string{}
+	i := 1 // skip '{'
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, "", fmt.Errorf("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, strings.TrimSpace(s[i+1:]), nil
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' {
+			i++
+		}
+		name := s[start:i]
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			return nil, "", fmt.Errorf("expected '=' after label %q", name)
+		}
+		i++
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i >= len(s) || s[i] != '"' {
+			return nil, "", fmt.Errorf("expected quoted value for label %q", name)
+		}
+		i++
+
+		var b strings.Builder
+		closed := false
+		for i < len(s) {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				switch s[i+1] {
+				case 'n':
+					b.WriteByte('\n')
+				case '"':
+					b.WriteByte('"')
+				case '\\':
+					b.WriteByte('\\')
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(s[i+1])
+				}
+				i += 2
+				continue
+			}
+			if c == '"' {
+				closed = true
+				i++
+				break
+			}
+			b.WriteByte(c)
+			i++
+		}
+		if !closed {
+			return nil, "", fmt.Errorf("unterminated value for label %q", name)
+		}
+		labels[name] = b.String()
+	}
+}
+
+// findExemplar returns the index of the '#' that starts an exemplar, skipping
+// any '#' inside quoted label values.
+func findExemplar(line string) int {
+	inQuote := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inQuote {
+				i++
+			}
+		case '"':
+			inQuote = !inQuote
+		case '#':
+			if !inQuote {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseFloat accepts the special values used by both formats.
+func parseFloat(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf", "+inf", "inf":
+		return math.Inf(1), nil
+	case "-Inf", "-inf":
+		return math.Inf(-1), nil
+	case "NaN", "nan":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// floatTime converts fractional Unix seconds to time.Time.
+func floatTime(v float64) time.Time {
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+func unescapeHelp(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(s)
+}
+
+// labelKey builds a stable identity for a label set.
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/propagation/b3.go
+
+package propagation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// B3 header names.
+const (
+	HeaderB3             = "b3"
+	HeaderB3TraceID      = "X-B3-TraceId"
+	HeaderB3SpanID       = "X-B3-SpanId"
+	HeaderB3ParentSpanID = "X-B3-ParentSpanId"
+	HeaderB3Sampled      = "X-B3-Sampled"
+	HeaderB3Flags        = "X-B3-Flags"
+)
+
+// ParseB3 decodes a single b3 header:
+// "{TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}", where the last two
+// fields are optional. A sampling-only header ("0", "1" or "d") carries no
+// IDs and is rejected.
+func ParseB3(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return SpanContext{}, fmt.Errorf("%w: b3 %q", ErrMalformed, s)
+	}
+	sc, err := b3IDs(parts[0], parts[1])
+	if err != nil {
+		return SpanContext{}, err
+	}
+	if len(parts) > 2 {
+		if err := sc.setB3Sampling(parts[2]); err != nil {
+			return SpanContext{}, err
+		}
+	}
+	if len(parts) > 3 {
+		if !ValidSpanID(parts[3]) {
+			return SpanContext{}, fmt.Errorf("%w: parent %q", ErrInvalidSpanID, parts[3])
+		}
+		sc.ParentSpanID = parts[3]
+	}
+	return sc, nil
+}
+
+// B3 encodes sc as a single b3 header.
+func (sc SpanContext) B3() string {
+	s := sc.TraceID + "-" + sc.SpanID + "-" + sc.b3Sampling()
+	if sc.ParentSpanID != "" {
+		s += "-" + sc.ParentSpanID
+	}
+	return s
+}
+
+func (sc SpanContext) b3Sampling() string {
+	switch {
+	case sc.Debug:
+		return "d"
+	case sc.Sampled():
+		return "1"
+	}
+	return "0"
+}
+
+func (sc *SpanContext) setB3Sampling(v string) error {
+	switch strings.ToLower(v) {
+	case "d":
+		sc.Debug = true
+		sc.Flags |= FlagSampled
+	case "1", "true":
+		sc.Flags |= FlagSampled
+	case "0", "false":
+	default:
+		return fmt.Errorf("%w: b3 sampling state %q", ErrMalformed, v)
+	}
+	return nil
+}
+
+// b3IDs validates B3 trace and span IDs. 64-bit trace IDs are left-padded
+// to the 128-bit form model.TraceSpan uses.
+func b3IDs(traceID, spanID string) (SpanContext, error) {
+	traceID = strings.ToLower(traceID)
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if !ValidTraceID(traceID) {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceID, traceID)
+	}
+	spanID = strings.ToLower(spanID)
+	if !ValidSpanID(spanID) {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidSpanID, spanID)
+	}
+	return SpanContext{TraceID: traceID, SpanID: spanID}, nil
+}
+
+func injectB3Multi(c Carrier, sc SpanContext) {
+	c.Set(HeaderB3TraceID, sc.TraceID)
+	c.Set(HeaderB3SpanID, sc.SpanID)
+	if sc.ParentSpanID != "" {
+		c.Set(HeaderB3ParentSpanID, sc.ParentSpanID)
+	}
+	if sc.Debug {
+		c.Set(HeaderB3Flags, "1")
+		return
+	}
+	c.Set(HeaderB3Sampled, sc.b3Sampling())
+}
+
+func extractB3Multi(c Carrier) (SpanContext, error) {
+	traceID, spanID := c.Get(HeaderB3TraceID), c.Get(HeaderB3SpanID)
+	if traceID == "" && spanID == "" {
+		return SpanContext{}, fmt.Errorf("%w: no b3 headers", ErrMalformed)
+	}
+	sc, err := b3IDs(traceID, spanID)
+	if err != nil {
+		return SpanContext{}, err
+	}
+	if p := strings.ToLower(c.Get(HeaderB3ParentSpanID)); p != "" {
+		if !ValidSpanID(p) {
+			return SpanContext{}, fmt.Errorf("%w: parent %q", ErrInvalidSpanID, p)
+		}
+		sc.ParentSpanID = p
+	}
+	if c.Get(HeaderB3Flags) == "1" {
+		sc.Debug = true
+		sc.Flags |= FlagSampled
+	} else if v := c.Get(HeaderB3Sampled); v != "" {
+		if err := sc.setB3Sampling(v); err != nil {
+			return SpanContext{}, err
+		}
+	}
+	return sc, nil
+}
@@ -0,0 +1,135 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/propagation/grpc.go
+
+package propagation
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataCarrier adapts gRPC metadata. gRPC lowercases keys, so lookups
+// and writes use lowercase keys.
+type MetadataCarrier metadata.MD
+
+// Get returns the first value for key.
+func (m MetadataCarrier) Get(key string) string {
+	if v := metadata.MD(m).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Set replaces the values for key.
+func (m MetadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(strings.ToLower(key), value)
+}
+
+type contextKey struct{}
+
+// ContextWithSpanContext returns a context carrying sc, which the client
+// interceptors propagate on outgoing calls.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context stored by
+// ContextWithSpanContext or the server interceptors.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(contextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// NewOutgoingContext adds sc to the outgoing gRPC metadata of ctx, e.g.
+// before calling CommandService.ExecuteCommand or opening StreamService.Stream.
+func NewOutgoingContext(ctx context.Context, sc SpanContext, formats ...Format) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	Inject(MetadataCarrier(md), sc, formats...)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// FromIncomingContext extracts the caller's span context from incoming
+// gRPC metadata.
+func FromIncomingContext(ctx context.Context) (SpanContext, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return SpanContext{}, false
+	}
+	return Extract(MetadataCarrier(md))
+}
+
+// UnaryClientInterceptor propagates the span context of each call's
+// context (see ContextWithSpanContext) in the given formats.
+func UnaryClientInterceptor(formats ...Format) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if sc, ok := SpanContextFromContext(ctx); ok {
+			ctx = NewOutgoingContext(ctx, sc, formats...)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streaming calls.
+func StreamClientInterceptor(formats ...Format) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if sc, ok := SpanContextFromContext(ctx); ok {
+			ctx = NewOutgoingContext(ctx, sc, formats...)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// UnaryServerInterceptor stores the caller's span context in the handler's
+// context, retrievable with SpanContextFromContext.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if sc, ok := FromIncomingContext(ctx); ok {
+			ctx = ContextWithSpanContext(ctx, sc)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if sc, ok := FromIncomingContext(ss.Context()); ok {
+			ss = &contextStream{ServerStream: ss, ctx: ContextWithSpanContext(ss.Context(), sc)}
+		}
+		return handler(srv, ss)
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,258 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/propagation/propagation.go
+
+// Package propagation carries trace context across process boundaries so
+// commands and webhook actions show up in traces. It encodes and decodes
+// W3C traceparent/tracestate and Zipkin B3 (single and multi header)
+// formats, injects them into ActionSpec headers, gRPC metadata and process
+// environments, and validates the hex IDs used by model.TraceSpan.
+package propagation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Format is a propagation header format.
+type Format string
+
+const (
+	FormatW3C     Format = "w3c"     // traceparent and tracestate
+	FormatB3      Format = "b3"      // single "b3" header
+	FormatB3Multi Format = "b3multi" // X-B3-* headers
+)
+
+// DefaultFormats are injected when no format is given.
+var DefaultFormats = []Format{FormatW3C}
+
+var (
+	ErrInvalidTraceID = errors.New("propagation: invalid trace id")
+	ErrInvalidSpanID  = errors.New("propagation: invalid span id")
+	ErrMalformed      = errors.New("propagation: malformed header")
+)
+
+// FlagSampled is the W3C trace-flags bit for a sampled trace.
+const FlagSampled byte = 0x01
+
+// SpanContext identifies the span a call belongs to.
+type SpanContext struct {
+	TraceID      string // 32 lowercase hex digits
+	SpanID       string // 16 lowercase hex digits
+	ParentSpanID string // B3 only
+	Flags        byte   // W3C trace-flags
+	Debug        bool   // B3 debug flag, implies sampled
+	TraceState   TraceState
+}
+
+// IsValid reports whether both IDs are well formed.
+func (sc SpanContext) IsValid() bool {
+	return ValidTraceID(sc.TraceID) && ValidSpanID(sc.SpanID)
+}
+
+// Sampled reports whether the sampled flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&FlagSampled != 0 || sc.Debug
+}
+
+// FromSpan returns the context of a span, for propagating to its children.
+// Spans are treated as sampled since they were recorded.
+func FromSpan(s *model.TraceSpan) SpanContext {
+	return SpanContext{
+		TraceID:      strings.ToLower(s.TraceID),
+		SpanID:       strings.ToLower(s.SpanID),
+		ParentSpanID: strings.ToLower(s.ParentSpanID),
+		Flags:        FlagSampled,
+	}
+}
+
+// Child returns a context for a new span under sc, with a fresh span ID.
+func (sc SpanContext) Child() SpanContext {
+	child := sc
+	child.ParentSpanID = sc.SpanID
+	child.SpanID = NewSpanID()
+	return child
+}
+
+// ValidTraceID reports whether s is a 32 digit lowercase hex trace ID that
+// is not all zeros, the form model.TraceSpan uses.
+func ValidTraceID(s string) bool {
+	return validID(s, 32)
+}
+
+// ValidSpanID reports whether s is a 16 digit lowercase hex span ID that is
+// not all zeros.
+func ValidSpanID(s string) bool {
+	return validID(s, 16)
+}
+
+func validID(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	zero := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '0':
+		case c >= '1' && c <= '9', c >= 'a' && c <= 'f':
+			zero = false
+		default:
+			return false
+		}
+	}
+	return !zero
+}
+
+// NewTraceID returns a random trace ID.
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID returns a random span ID.
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic("propagation: crypto/rand failed: " + err.Error())
+		}
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
+
+// Carrier reads and writes propagation headers.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// MapCarrier adapts a header map such as ActionSpec.Headers. Lookups are
+// case-insensitive.
+type MapCarrier map[string]string
+
+// Get returns the value of key, ignoring case.
+func (m MapCarrier) Get(key string) string {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Set stores a value, replacing any existing key that differs only in case.
+func (m MapCarrier) Set(key, value string) {
+	for k := range m {
+		if k != key && strings.EqualFold(k, key) {
+			delete(m, k)
+		}
+	}
+	m[key] = value
+}
+
+// Inject writes sc into c in each format, or DefaultFormats if none are
+// given. Invalid contexts are not injected.
+func Inject(c Carrier, sc SpanContext, formats ...Format) {
+	if !sc.IsValid() {
+		return
+	}
+	if len(formats) == 0 {
+		formats = DefaultFormats
+	}
+	for _, f := range formats {
+		switch f {
+		case FormatW3C:
+			c.Set(HeaderTraceparent, sc.Traceparent())
+			if len(sc.TraceState) > 0 {
+				c.Set(HeaderTracestate, sc.TraceState.String())
+			}
+		case FormatB3:
+			c.Set(HeaderB3, sc.B3())
+		case FormatB3Multi:
+			injectB3Multi(c, sc)
+		}
+	}
+}
+
+// Extract reads a span context from c, trying W3C, then single B3, then
+// multi-header B3.
+func Extract(c Carrier) (SpanContext, bool) {
+	if v := c.Get(HeaderTraceparent); v != "" {
+		if sc, err := ParseTraceparent(v); err == nil {
+			if ts := c.Get(HeaderTracestate); ts != "" {
+				// An invalid tracestate is discarded, not the whole context.
+				sc.TraceState, _ = ParseTraceState(ts)
+			}
+			return sc, true
+		}
+	}
+	if v := c.Get(HeaderB3); v != "" {
+		if sc, err := ParseB3(v); err == nil {
+			return sc, true
+		}
+	}
+	if sc, err := extractB3Multi(c); err == nil {
+		return sc, true
+	}
+	return SpanContext{}, false
+}
+
+// InjectAction adds trace headers to a webhook action, allocating Headers
+// if needed.
+func InjectAction(a *model.ActionSpec, sc SpanContext, formats ...Format) {
+	if !sc.IsValid() {
+		return
+	}
+	if a.Headers == nil {
+		a.Headers = map[string]string{}
+	}
+	Inject(MapCarrier(a.Headers), sc, formats...)
+}
+
+// Environ returns TRACEPARENT and TRACESTATE environment variables for a
+// script or command, following the OpenTelemetry environment carrier
+// convention.
+func Environ(sc SpanContext) []string {
+	if !sc.IsValid() {
+		return nil
+	}
+	env := []string{"TRACEPARENT=" + sc.Traceparent()}
+	if len(sc.TraceState) > 0 {
+		env = append(env, "TRACESTATE="+sc.TraceState.String())
+	}
+	return env
+}
@@ -0,0 +1,151 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/propagation/w3c.go
+
+package propagation
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// W3C Trace Context header names.
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// maxTraceStateMembers is the W3C limit on tracestate list members.
+const maxTraceStateMembers = 32
+
+var (
+	traceStateKey   = regexp.MustCompile(`^(?:[a-z][a-z0-9_\-*/]{0,255}|[a-z0-9][a-z0-9_\-*/]{0,240}@[a-z][a-z0-9_\-*/]{0,13})$`)
+	traceStateValue = regexp.MustCompile(`^[\x20-\x2b\x2d-\x3c\x3e-\x7e]{0,255}[\x21-\x2b\x2d-\x3c\x3e-\x7e]$`)
+)
+
+// ParseTraceparent decodes a traceparent header ("00-<trace>-<span>-<flags>").
+// Future versions are accepted as long as the first four fields parse.
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[3]) != 2 {
+		return SpanContext{}, fmt.Errorf("%w: traceparent %q", ErrMalformed, s)
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("%w: traceparent version %q", ErrMalformed, parts[0])
+	}
+	if !ValidTraceID(parts[1]) {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceID, parts[1])
+	}
+	if !ValidSpanID(parts[2]) {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidSpanID, parts[2])
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, fmt.Errorf("%w: traceparent flags %q", ErrMalformed, parts[3])
+	}
+	return SpanContext{TraceID: parts[1], SpanID: parts[2], Flags: flags[0]}, nil
+}
+
+// Traceparent encodes sc as a version 00 traceparent header.
+func (sc SpanContext) Traceparent() string {
+	flags := sc.Flags
+	if sc.Debug {
+		flags |= FlagSampled
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// TraceStateMember is one vendor entry of a tracestate header.
+type TraceStateMember struct {
+	Key   string
+	Value string
+}
+
+// TraceState is an ordered tracestate list, most recently updated first.
+type TraceState []TraceStateMember
+
+// ParseTraceState decodes a tracestate header. Empty list members are
+// skipped; an invalid or duplicate key makes the whole header invalid.
+func ParseTraceState(s string) (TraceState, error) {
+	var ts TraceState
+	seen := map[string]bool{}
+	for _, m := range strings.Split(s, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(m, "=")
+		if !ok || !traceStateKey.MatchString(k) || !traceStateValue.MatchString(v) {
+			return nil, fmt.Errorf("%w: tracestate member %q", ErrMalformed, m)
+		}
+		if seen[k] {
+			return nil, fmt.Errorf("%w: duplicate tracestate key %q", ErrMalformed, k)
+		}
+		seen[k] = true
+		ts = append(ts, TraceStateMember{Key: k, Value: v})
+	}
+	if len(ts) > maxTraceStateMembers {
+		return nil, fmt.Errorf("%w: tracestate has %d members", ErrMalformed, len(ts))
+	}
+	return ts, nil
+}
+
+// Get returns the value for key.
+func (ts TraceState) Get(key string) string {
+	for _, m := range ts {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// Insert returns a copy with key set to value and moved to the front, as
+// W3C requires when a vendor updates its entry. The oldest members are
+// dropped beyond the 32 member limit.
+func (ts TraceState) Insert(key, value string) (TraceState, error) {
+	if !traceStateKey.MatchString(key) || !traceStateValue.MatchString(value) {
+		return ts, fmt.Errorf("%w: tracestate member %s=%s", ErrMalformed, key, value)
+	}
+	out := TraceState{{Key: key, Value: value}}
+	for _, m := range ts {
+		if m.Key != key {
+			out = append(out, m)
+		}
+	}
+	if len(out) > maxTraceStateMembers {
+		out = out[:maxTraceStateMembers]
+	}
+	return out, nil
+}
+
+// String encodes the list as a tracestate header.
+func (ts TraceState) String() string {
+	parts := make([]string, len(ts))
+	for i, m := range ts {
+		parts[i] = m.Key + "=" + m.Value
+	}
+	return strings.Join(parts, ",")
+}
@@ -22,13 +22,27 @@ const (
 )
 
 type CommandRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
-	CommandType   string                 `protobuf:"bytes,2,opt,name=command_type,json=commandType,proto3" json:"command_type,omitempty"` // "shell" or "ansible"
-	Command       string                 `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`                            // Shell command (if type = shell) or playbook content (if type = ansible)
-	Args          []string               `protobuf:"bytes,4,rep,name=args,proto3" json:"args,omitempty"`                                  // Optional extra args
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AgentId        string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	CommandType    string                 `protobuf:"bytes,2,opt,name=command_type,json=commandType,proto3" json:"command_type,omitempty"`                                        // "shell" or "ansible"
+	Command        string                 `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`                                                                   // Shell command (if type = shell) or playbook content (if type = ansible)
+	Args           []string               `protobuf:"bytes,4,rep,name=args,proto3" json:"args,omitempty"`                                                                         // Optional extra args
+	RequestId      string                 `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`                                              // Correlates output chunks and the response with this request
+	TimeoutSeconds int32                  `protobuf:"varint,6,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`                              // Kill the command after this long; 0 = agent default
+	Env            map[string]string      `protobuf:"bytes,7,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Extra environment variables
+	WorkingDir     string                 `protobuf:"bytes,8,opt,name=working_dir,json=workingDir,proto3" json:"working_dir,omitempty"`                                           // Working directory; empty = agent default
+	RunAsUser      string                 `protobuf:"bytes,9,opt,name=run_as_user,json=runAsUser,proto3" json:"run_as_user,omitempty"`                                            // Run as this user; empty = agent user
+	Stdin          []byte                 `protobuf:"bytes,10,opt,name=stdin,proto3" json:"stdin,omitempty"`                                                                      // Written to the command's standard input
+	StreamOutput   bool                   `protobuf:"varint,11,opt,name=stream_output,json=streamOutput,proto3" json:"stream_output,omitempty"`                                   // Send CommandOutputChunk messages while the command runs
+	// Server signature, verified by the agent against a pinned public key.
+	RequestedBy       string `protobuf:"bytes,12,opt,name=requested_by,json=requestedBy,proto3" json:"requested_by,omitempty"` // User who issued the command, checked by agent policy
+	Nonce             string `protobuf:"bytes,13,opt,name=nonce,proto3" json:"nonce,omitempty"`                                // Random, single use
+	IssuedAtUnixNano  int64  `protobuf:"varint,14,opt,name=issued_at_unix_nano,json=issuedAtUnixNano,proto3" json:"issued_at_unix_nano,omitempty"`
+	ExpiresAtUnixNano int64  `protobuf:"varint,15,opt,name=expires_at_unix_nano,json=expiresAtUnixNano,proto3" json:"expires_at_unix_nano,omitempty"`
+	KeyId             string `protobuf:"bytes,16,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"` // Which server key signed the request
+	Signature         []byte `protobuf:"bytes,17,opt,name=signature,proto3" json:"signature,omitempty"`      // Ed25519 over the canonical request, see shared/command
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *CommandRequest) Reset() {
@@ -89,13 +103,109 @@ func (x *CommandRequest) GetArgs() []string {
 	return nil
 }
 
+func (x *CommandRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *CommandRequest) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetWorkingDir() string {
+	if x != nil {
+		return x.WorkingDir
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetRunAsUser() string {
+	if x != nil {
+		return x.RunAsUser
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetStdin() []byte {
+	if x != nil {
+		return x.Stdin
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetStreamOutput() bool {
+	if x != nil {
+		return x.StreamOutput
+	}
+	return false
+}
+
+func (x *CommandRequest) GetRequestedBy() string {
+	if x != nil {
+		return x.RequestedBy
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetIssuedAtUnixNano() int64 {
+	if x != nil {
+		return x.IssuedAtUnixNano
+	}
+	return 0
+}
+
+func (x *CommandRequest) GetExpiresAtUnixNano() int64 {
+	if x != nil {
+		return x.ExpiresAtUnixNano
+	}
+	return 0
+}
+
+func (x *CommandRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
 type CommandResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Output        string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`                                 // stdout + stderr
-	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"` // if failed
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Success           bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Output            string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`                                 // stdout + stderr
+	ErrorMessage      string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"` // if failed
+	RequestId         string                 `protobuf:"bytes,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	ExitCode          int32                  `protobuf:"varint,5,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	DurationMs        int64                  `protobuf:"varint,6,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	StartedAtUnixNano int64                  `protobuf:"varint,7,opt,name=started_at_unix_nano,json=startedAtUnixNano,proto3" json:"started_at_unix_nano,omitempty"`
+	TimedOut          bool                   `protobuf:"varint,8,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *CommandResponse) Reset() {
@@ -149,22 +259,309 @@ func (x *CommandResponse) GetErrorMessage() string {
 	return ""
 }
 
+func (x *CommandResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *CommandResponse) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *CommandResponse) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *CommandResponse) GetStartedAtUnixNano() int64 {
+	if x != nil {
+		return x.StartedAtUnixNano
+	}
+	return 0
+}
+
+func (x *CommandResponse) GetTimedOut() bool {
+	if x != nil {
+		return x.TimedOut
+	}
+	return false
+}
+
+type CommandOutputChunk struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	RequestId         string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Stream            string                 `protobuf:"bytes,2,opt,name=stream,proto3" json:"stream,omitempty"` // "stdout" or "stderr"
+	Data              []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Sequence          int64                  `protobuf:"varint,4,opt,name=sequence,proto3" json:"sequence,omitempty"` // increases by one per chunk of a request, from 0
+	TimestampUnixNano int64                  `protobuf:"varint,5,opt,name=timestamp_unix_nano,json=timestampUnixNano,proto3" json:"timestamp_unix_nano,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CommandOutputChunk) Reset() {
+	*x = CommandOutputChunk{}
+	mi := &file_command_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandOutputChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandOutputChunk) ProtoMessage() {}
+
+func (x *CommandOutputChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_command_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandOutputChunk.ProtoReflect.Descriptor instead.
+func (*CommandOutputChunk) Descriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CommandOutputChunk) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *CommandOutputChunk) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+func (x *CommandOutputChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *CommandOutputChunk) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *CommandOutputChunk) GetTimestampUnixNano() int64 {
+	if x != nil {
+		return x.TimestampUnixNano
+	}
+	return 0
+}
+
+type CommandStreamResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*CommandStreamResponse_Output
+	//	*CommandStreamResponse_Result
+	Event         isCommandStreamResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandStreamResponse) Reset() {
+	*x = CommandStreamResponse{}
+	mi := &file_command_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandStreamResponse) ProtoMessage() {}
+
+func (x *CommandStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_command_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandStreamResponse.ProtoReflect.Descriptor instead.
+func (*CommandStreamResponse) Descriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CommandStreamResponse) GetEvent() isCommandStreamResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *CommandStreamResponse) GetOutput() *CommandOutputChunk {
+	if x != nil {
+		if x, ok := x.Event.(*CommandStreamResponse_Output); ok {
+			return x.Output
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamResponse) GetResult() *CommandResponse {
+	if x != nil {
+		if x, ok := x.Event.(*CommandStreamResponse_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isCommandStreamResponse_Event interface {
+	isCommandStreamResponse_Event()
+}
+
+type CommandStreamResponse_Output struct {
+	Output *CommandOutputChunk `protobuf:"bytes,1,opt,name=output,proto3,oneof"`
+}
+
+type CommandStreamResponse_Result struct {
+	Result *CommandResponse `protobuf:"bytes,2,opt,name=result,proto3,oneof"` // always the last message
+}
+
+func (*CommandStreamResponse_Output) isCommandStreamResponse_Event() {}
+
+func (*CommandStreamResponse_Result) isCommandStreamResponse_Event() {}
+
+type CommandCancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandCancel) Reset() {
+	*x = CommandCancel{}
+	mi := &file_command_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandCancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandCancel) ProtoMessage() {}
+
+func (x *CommandCancel) ProtoReflect() protoreflect.Message {
+	mi := &file_command_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandCancel.ProtoReflect.Descriptor instead.
+func (*CommandCancel) Descriptor() ([]byte, []int) {
+	return file_command_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CommandCancel) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *CommandCancel) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
 var File_command_proto protoreflect.FileDescriptor
 
 const file_command_proto_rawDesc = "" +
 	"\n" +
-	"\rcommand.proto\x12\x05proto\"|\n" +
+	"\rcommand.proto\x12\x05proto\"\xf8\x04\n" +
 	"\x0eCommandRequest\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12!\n" +
 	"\fcommand_type\x18\x02 \x01(\tR\vcommandType\x12\x18\n" +
 	"\acommand\x18\x03 \x01(\tR\acommand\x12\x12\n" +
-	"\x04args\x18\x04 \x03(\tR\x04args\"h\n" +
+	"\x04args\x18\x04 \x03(\tR\x04args\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x05 \x01(\tR\trequestId\x12'\n" +
+	"\x0ftimeout_seconds\x18\x06 \x01(\x05R\x0etimeoutSeconds\x120\n" +
+	"\x03env\x18\a \x03(\v2\x1e.proto.CommandRequest.EnvEntryR\x03env\x12\x1f\n" +
+	"\vworking_dir\x18\b \x01(\tR\n" +
+	"workingDir\x12\x1e\n" +
+	"\vrun_as_user\x18\t \x01(\tR\trunAsUser\x12\x14\n" +
+	"\x05stdin\x18\n" +
+	" \x01(\fR\x05stdin\x12#\n" +
+	"\rstream_output\x18\v \x01(\bR\fstreamOutput\x12!\n" +
+	"\frequested_by\x18\f \x01(\tR\vrequestedBy\x12\x14\n" +
+	"\x05nonce\x18\r \x01(\tR\x05nonce\x12-\n" +
+	"\x13issued_at_unix_nano\x18\x0e \x01(\x03R\x10issuedAtUnixNano\x12/\n" +
+	"\x14expires_at_unix_nano\x18\x0f \x01(\x03R\x11expiresAtUnixNano\x12\x15\n" +
+	"\x06key_id\x18\x10 \x01(\tR\x05keyId\x12\x1c\n" +
+	"\tsignature\x18\x11 \x01(\fR\tsignature\x1a6\n" +
+	"\bEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x93\x02\n" +
 	"\x0fCommandResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x16\n" +
 	"\x06output\x18\x02 \x01(\tR\x06output\x12#\n" +
-	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage2Q\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x04 \x01(\tR\trequestId\x12\x1b\n" +
+	"\texit_code\x18\x05 \x01(\x05R\bexitCode\x12\x1f\n" +
+	"\vduration_ms\x18\x06 \x01(\x03R\n" +
+	"durationMs\x12/\n" +
+	"\x14started_at_unix_nano\x18\a \x01(\x03R\x11startedAtUnixNano\x12\x1b\n" +
+	"\ttimed_out\x18\b \x01(\bR\btimedOut\"\xab\x01\n" +
+	"\x12CommandOutputChunk\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x16\n" +
+	"\x06stream\x18\x02 \x01(\tR\x06stream\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\x03R\bsequence\x12.\n" +
+	"\x13timestamp_unix_nano\x18\x05 \x01(\x03R\x11timestampUnixNano\"\x87\x01\n" +
+	"\x15CommandStreamResponse\x123\n" +
+	"\x06output\x18\x01 \x01(\v2\x19.proto.CommandOutputChunkH\x00R\x06output\x120\n" +
+	"\x06result\x18\x02 \x01(\v2\x16.proto.CommandResponseH\x00R\x06resultB\a\n" +
+	"\x05event\"F\n" +
+	"\rCommandCancel\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason2\xa0\x01\n" +
 	"\x0eCommandService\x12?\n" +
-	"\x0eExecuteCommand\x12\x15.proto.CommandRequest\x1a\x16.proto.CommandResponseB.Z,github.com/aaronlmathis/gosight-shared/protob\x06proto3"
+	"\x0eExecuteCommand\x12\x15.proto.CommandRequest\x1a\x16.proto.CommandResponse\x12M\n" +
+	"\x14ExecuteCommandStream\x12\x15.proto.CommandRequest\x1a\x1c.proto.CommandStreamResponse0\x01B.Z,github.com/aaronlmathis/gosight-shared/protob\x06proto3"
 
 var (
 	file_command_proto_rawDescOnce sync.Once
@@ -178,19 +575,28 @@ func file_command_proto_rawDescGZIP() []byte {
 	return file_command_proto_rawDescData
 }
 
-var file_command_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_command_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_command_proto_goTypes = []any{
-	(*CommandRequest)(nil),  // 0: proto.CommandRequest
-	(*CommandResponse)(nil), // 1: proto.CommandResponse
+	(*CommandRequest)(nil),        // 0: proto.CommandRequest
+	(*CommandResponse)(nil),       // 1: proto.CommandResponse
+	(*CommandOutputChunk)(nil),    // 2: proto.CommandOutputChunk
+	(*CommandStreamResponse)(nil), // 3: proto.CommandStreamResponse
+	(*CommandCancel)(nil),         // 4: proto.CommandCancel
+	nil,                           // 5: proto.CommandRequest.EnvEntry
 }
 var file_command_proto_depIdxs = []int32{
-	0, // 0: proto.CommandService.ExecuteCommand:input_type -> proto.CommandRequest
-	1, // 1: proto.CommandService.ExecuteCommand:output_type -> proto.CommandResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	5, // 0: proto.CommandRequest.env:type_name -> proto.CommandRequest.EnvEntry
+	2, // 1: proto.CommandStreamResponse.output:type_name -> proto.CommandOutputChunk
+	1, // 2: proto.CommandStreamResponse.result:type_name -> proto.CommandResponse
+	0, // 3: proto.CommandService.ExecuteCommand:input_type -> proto.CommandRequest
+	0, // 4: proto.CommandService.ExecuteCommandStream:input_type -> proto.CommandRequest
+	1, // 5: proto.CommandService.ExecuteCommand:output_type -> proto.CommandResponse
+	3, // 6: proto.CommandService.ExecuteCommandStream:output_type -> proto.CommandStreamResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_command_proto_init() }
@@ -198,13 +604,17 @@ func file_command_proto_init() {
 	if File_command_proto != nil {
 		return
 	}
+	file_command_proto_msgTypes[3].OneofWrappers = []any{
+		(*CommandStreamResponse_Output)(nil),
+		(*CommandStreamResponse_Result)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_command_proto_rawDesc), len(file_command_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
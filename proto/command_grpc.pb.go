@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	CommandService_ExecuteCommand_FullMethodName = "/proto.CommandService/ExecuteCommand"
+	CommandService_ExecuteCommand_FullMethodName       = "/proto.CommandService/ExecuteCommand"
+	CommandService_ExecuteCommandStream_FullMethodName = "/proto.CommandService/ExecuteCommandStream"
 )
 
 // CommandServiceClient is the client API for CommandService service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CommandServiceClient interface {
 	ExecuteCommand(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandResponse, error)
+	ExecuteCommandStream(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CommandStreamResponse], error)
 }
 
 type commandServiceClient struct {
@@ -47,11 +49,31 @@ func (c *commandServiceClient) ExecuteCommand(ctx context.Context, in *CommandRe
 	return out, nil
 }
 
+func (c *commandServiceClient) ExecuteCommandStream(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CommandStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CommandService_ServiceDesc.Streams[0], CommandService_ExecuteCommandStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CommandRequest, CommandStreamResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CommandService_ExecuteCommandStreamClient = grpc.ServerStreamingClient[CommandStreamResponse]
+
 // CommandServiceServer is the server API for CommandService service.
 // All implementations must embed UnimplementedCommandServiceServer
 // for forward compatibility.
 type CommandServiceServer interface {
 	ExecuteCommand(context.Context, *CommandRequest) (*CommandResponse, error)
+	ExecuteCommandStream(*CommandRequest, grpc.ServerStreamingServer[CommandStreamResponse]) error
 	mustEmbedUnimplementedCommandServiceServer()
 }
 
@@ -65,6 +87,9 @@ type UnimplementedCommandServiceServer struct{}
 func (UnimplementedCommandServiceServer) ExecuteCommand(context.Context, *CommandRequest) (*CommandResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExecuteCommand not implemented")
 }
+func (UnimplementedCommandServiceServer) ExecuteCommandStream(*CommandRequest, grpc.ServerStreamingServer[CommandStreamResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteCommandStream not implemented")
+}
 func (UnimplementedCommandServiceServer) mustEmbedUnimplementedCommandServiceServer() {}
 func (UnimplementedCommandServiceServer) testEmbeddedByValue()                        {}
 
@@ -104,6 +129,17 @@ func _CommandService_ExecuteCommand_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CommandService_ExecuteCommandStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CommandRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CommandServiceServer).ExecuteCommandStream(m, &grpc.GenericServerStream[CommandRequest, CommandStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CommandService_ExecuteCommandStreamServer = grpc.ServerStreamingServer[CommandStreamResponse]
+
 // CommandService_ServiceDesc is the grpc.ServiceDesc for CommandService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -116,6 +152,12 @@ var CommandService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _CommandService_ExecuteCommand_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExecuteCommandStream",
+			Handler:       _CommandService_ExecuteCommandStream_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "command.proto",
 }
@@ -117,6 +117,7 @@ type StreamPayload struct {
 	//	*StreamPayload_CommandRequest
 	//	*StreamPayload_CommandResponse
 	//	*StreamPayload_Process
+	//	*StreamPayload_CommandOutput
 	Payload       isStreamPayload_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -195,6 +196,15 @@ func (x *StreamPayload) GetProcess() *ProcessWrapper {
 	return nil
 }
 
+func (x *StreamPayload) GetCommandOutput() *CommandOutputChunk {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamPayload_CommandOutput); ok {
+			return x.CommandOutput
+		}
+	}
+	return nil
+}
+
 type isStreamPayload_Payload interface {
 	isStreamPayload_Payload()
 }
@@ -215,6 +225,10 @@ type StreamPayload_Process struct {
 	Process *ProcessWrapper `protobuf:"bytes,5,opt,name=process,proto3,oneof"`
 }
 
+type StreamPayload_CommandOutput struct {
+	CommandOutput *CommandOutputChunk `protobuf:"bytes,6,opt,name=command_output,json=commandOutput,proto3,oneof"`
+}
+
 func (*StreamPayload_Metric) isStreamPayload_Payload() {}
 
 func (*StreamPayload_CommandRequest) isStreamPayload_Payload() {}
@@ -223,11 +237,14 @@ func (*StreamPayload_CommandResponse) isStreamPayload_Payload() {}
 
 func (*StreamPayload_Process) isStreamPayload_Payload() {}
 
+func (*StreamPayload_CommandOutput) isStreamPayload_Payload() {}
+
 type StreamResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
 	StatusCode    int32                  `protobuf:"varint,2,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
 	Command       *CommandRequest        `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"` // optional
+	Cancel        *CommandCancel         `protobuf:"bytes,4,opt,name=cancel,proto3" json:"cancel,omitempty"`   // optional: stop a running command
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -283,6 +300,13 @@ func (x *StreamResponse) GetCommand() *CommandRequest {
 	return nil
 }
 
+func (x *StreamResponse) GetCancel() *CommandCancel {
+	if x != nil {
+		return x.Cancel
+	}
+	return nil
+}
+
 var File_stream_proto protoreflect.FileDescriptor
 
 const file_stream_proto_rawDesc = "" +
@@ -293,18 +317,20 @@ const file_stream_proto_rawDesc = "" +
 	"rawPayload\"1\n" +
 	"\x0eProcessWrapper\x12\x1f\n" +
 	"\vraw_payload\x18\x01 \x01(\fR\n" +
-	"rawPayload\"\x84\x02\n" +
+	"rawPayload\"\xc8\x02\n" +
 	"\rStreamPayload\x12.\n" +
 	"\x06metric\x18\x01 \x01(\v2\x14.proto.MetricWrapperH\x00R\x06metric\x12@\n" +
 	"\x0fcommand_request\x18\x02 \x01(\v2\x15.proto.CommandRequestH\x00R\x0ecommandRequest\x12C\n" +
 	"\x10command_response\x18\x03 \x01(\v2\x16.proto.CommandResponseH\x00R\x0fcommandResponse\x121\n" +
-	"\aprocess\x18\x05 \x01(\v2\x15.proto.ProcessWrapperH\x00R\aprocessB\t\n" +
-	"\apayload\"z\n" +
+	"\aprocess\x18\x05 \x01(\v2\x15.proto.ProcessWrapperH\x00R\aprocess\x12B\n" +
+	"\x0ecommand_output\x18\x06 \x01(\v2\x19.proto.CommandOutputChunkH\x00R\rcommandOutputB\t\n" +
+	"\apayload\"\xa8\x01\n" +
 	"\x0eStreamResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1f\n" +
 	"\vstatus_code\x18\x02 \x01(\x05R\n" +
 	"statusCode\x12/\n" +
-	"\acommand\x18\x03 \x01(\v2\x15.proto.CommandRequestR\acommand2J\n" +
+	"\acommand\x18\x03 \x01(\v2\x15.proto.CommandRequestR\acommand\x12,\n" +
+	"\x06cancel\x18\x04 \x01(\v2\x14.proto.CommandCancelR\x06cancel2J\n" +
 	"\rStreamService\x129\n" +
 	"\x06Stream\x12\x14.proto.StreamPayload\x1a\x15.proto.StreamResponse(\x010\x01B.Z,github.com/aaronlmathis/gosight-shared/protob\x06proto3"
 
@@ -322,26 +348,30 @@ func file_stream_proto_rawDescGZIP() []byte {
 
 var file_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_stream_proto_goTypes = []any{
-	(*MetricWrapper)(nil),   // 0: proto.MetricWrapper
-	(*ProcessWrapper)(nil),  // 1: proto.ProcessWrapper
-	(*StreamPayload)(nil),   // 2: proto.StreamPayload
-	(*StreamResponse)(nil),  // 3: proto.StreamResponse
-	(*CommandRequest)(nil),  // 4: proto.CommandRequest
-	(*CommandResponse)(nil), // 5: proto.CommandResponse
+	(*MetricWrapper)(nil),      // 0: proto.MetricWrapper
+	(*ProcessWrapper)(nil),     // 1: proto.ProcessWrapper
+	(*StreamPayload)(nil),      // 2: proto.StreamPayload
+	(*StreamResponse)(nil),     // 3: proto.StreamResponse
+	(*CommandRequest)(nil),     // 4: proto.CommandRequest
+	(*CommandResponse)(nil),    // 5: proto.CommandResponse
+	(*CommandOutputChunk)(nil), // 6: proto.CommandOutputChunk
+	(*CommandCancel)(nil),      // 7: proto.CommandCancel
 }
 var file_stream_proto_depIdxs = []int32{
 	0, // 0: proto.StreamPayload.metric:type_name -> proto.MetricWrapper
 	4, // 1: proto.StreamPayload.command_request:type_name -> proto.CommandRequest
 	5, // 2: proto.StreamPayload.command_response:type_name -> proto.CommandResponse
 	1, // 3: proto.StreamPayload.process:type_name -> proto.ProcessWrapper
-	4, // 4: proto.StreamResponse.command:type_name -> proto.CommandRequest
-	2, // 5: proto.StreamService.Stream:input_type -> proto.StreamPayload
-	3, // 6: proto.StreamService.Stream:output_type -> proto.StreamResponse
-	6, // [6:7] is the sub-list for method output_type
-	5, // [5:6] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	6, // 4: proto.StreamPayload.command_output:type_name -> proto.CommandOutputChunk
+	4, // 5: proto.StreamResponse.command:type_name -> proto.CommandRequest
+	7, // 6: proto.StreamResponse.cancel:type_name -> proto.CommandCancel
+	2, // 7: proto.StreamService.Stream:input_type -> proto.StreamPayload
+	3, // 8: proto.StreamService.Stream:output_type -> proto.StreamResponse
+	8, // [8:9] is the sub-list for method output_type
+	7, // [7:8] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_stream_proto_init() }
@@ -355,6 +385,7 @@ func file_stream_proto_init() {
 		(*StreamPayload_CommandRequest)(nil),
 		(*StreamPayload_CommandResponse)(nil),
 		(*StreamPayload_Process)(nil),
+		(*StreamPayload_CommandOutput)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
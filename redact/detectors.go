@@ -0,0 +1,154 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/redact/detectors.go
+
+package redact
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Detector is a named pattern. If the pattern has capture groups, only the
+// first group is redacted and the surrounding text is kept.
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate, when set, must accept the matched text for it to be
+	// redacted (e.g. the Luhn check for card numbers).
+	Validate func(match string) bool
+}
+
+// Built-in detector names.
+const (
+	DetectorJWT            = "jwt"
+	DetectorAWSAccessKey   = "aws_access_key"
+	DetectorAWSSecretKey   = "aws_secret_key"
+	DetectorBearer         = "bearer"
+	DetectorBasicAuth      = "basic_auth"
+	DetectorURLCredentials = "url_credentials"
+	DetectorPrivateKey     = "private_key"
+	DetectorCreditCard     = "credit_card"
+	DetectorEmail          = "email"
+	DetectorIP             = "ip"
+)
+
+// Detectors are the built-in detectors by name.
+var Detectors = map[string]Detector{
+	DetectorJWT: {
+		Name:    DetectorJWT,
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`),
+	},
+	DetectorAWSAccessKey: {
+		Name:    DetectorAWSAccessKey,
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`),
+	},
+	DetectorAWSSecretKey: {
+		Name:    DetectorAWSSecretKey,
+		Pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`),
+	},
+	DetectorBearer: {
+		Name:    DetectorBearer,
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{8,}=*)`),
+	},
+	DetectorBasicAuth: {
+		Name:    DetectorBasicAuth,
+		Pattern: regexp.MustCompile(`(?i)\bbasic\s+([A-Za-z0-9+/]{8,}=*)`),
+	},
+	DetectorURLCredentials: {
+		Name:    DetectorURLCredentials,
+		Pattern: regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`),
+	},
+	DetectorPrivateKey: {
+		Name:    DetectorPrivateKey,
+		Pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	},
+	DetectorCreditCard: {
+		Name:     DetectorCreditCard,
+		Pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Validate: luhn,
+	},
+	DetectorEmail: {
+		Name:    DetectorEmail,
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	},
+	DetectorIP: {
+		Name: DetectorIP,
+		// IPv6 needs all eight groups or "::" compression, so times such as
+		// 12:34:56 and MAC addresses are not matched.
+		Pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b` +
+			`|\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b` +
+			`|(?:\b[0-9A-Fa-f]{1,4}(?::[0-9A-Fa-f]{1,4})*)?::(?:[0-9A-Fa-f]{1,4}(?::[0-9A-Fa-f]{1,4})*\b)?`),
+		Validate: validIP,
+	},
+}
+
+// DefaultDetectors are enabled when Config.Detectors is empty. IP addresses
+// are not redacted by default.
+var DefaultDetectors = []string{
+	DetectorPrivateKey, DetectorJWT, DetectorAWSAccessKey, DetectorAWSSecretKey,
+	DetectorBearer, DetectorBasicAuth, DetectorURLCredentials, DetectorCreditCard, DetectorEmail,
+}
+
+// DefaultSensitiveKeys are map keys whose whole value is redacted, compared
+// case-insensitively with '-' treated as '_'. Keys containing "password" or
+// "secret" are always sensitive.
+var DefaultSensitiveKeys = []string{
+	"authorization", "proxy_authorization", "cookie", "set_cookie",
+	"token", "access_token", "refresh_token", "id_token", "auth_token",
+	"api_key", "apikey", "x_api_key", "private_key", "client_secret", "passwd", "pwd",
+}
+
+// validIP accepts addresses net.ParseIP understands. Compressed IPv6 must
+// also contain a digit, so identifiers such as Foo::add are left alone.
+func validIP(s string) bool {
+	if net.ParseIP(s) == nil {
+		return false
+	}
+	return !strings.Contains(s, "::") || strings.ContainsAny(s, "0123456789")
+}
+
+// luhn validates a 13-19 digit card number, ignoring spaces and dashes.
+func luhn(s string) bool {
+	var digits []int
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,365 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/redact/redact.go
+
+// Package redact removes secrets and personal data from GoSight payloads.
+// Built-in detectors cover JWTs, AWS keys, bearer and basic credentials,
+// private keys, card numbers (Luhn checked), emails and, optionally, IP
+// addresses; custom regex rules can be added. Matches are masked, hashed or
+// dropped. Apply walks any model type, so logs, metrics, traces, events,
+// metadata, action specs and command results are all redacted the same way.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Mode is what happens to a match.
+type Mode string
+
+const (
+	// ModeMask replaces the match with "[REDACTED:<name>]".
+	ModeMask Mode = "mask"
+
+	// ModeHash replaces the match with "[<name>:<hash>]", a truncated
+	// SHA-256 (HMAC-SHA256 when HashKey is set), so equal values can still
+	// be correlated.
+	ModeHash Mode = "hash"
+
+	// ModeDrop removes the match from text, and removes map entries whose
+	// values contain a match.
+	ModeDrop Mode = "drop"
+)
+
+// Rule is a custom detector.
+type Rule struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`               // if it has groups, only the first is redacted
+	Mode    Mode   `yaml:"mode,omitempty" json:"mode,omitempty"` // defaults to Config.Mode
+}
+
+// Config selects detectors and modes.
+type Config struct {
+	// Detectors are built-in detector names; empty means DefaultDetectors.
+	Detectors []string `yaml:"detectors,omitempty" json:"detectors,omitempty"`
+
+	// Rules are custom detectors, run after the built-in ones.
+	Rules []Rule `yaml:"rules,omitempty" json:"rules,omitempty"`
+
+	// Mode applies to built-in detectors and sensitive keys. Default mask.
+	Mode Mode `yaml:"mode,omitempty" json:"mode,omitempty"`
+
+	// HashKey keys the hash mode so hashes cannot be brute-forced offline.
+	HashKey string `yaml:"hash_key,omitempty" json:"hash_key,omitempty"`
+
+	// SensitiveKeys replace DefaultSensitiveKeys when set.
+	SensitiveKeys []string `yaml:"sensitive_keys,omitempty" json:"sensitive_keys,omitempty"`
+}
+
+type detector struct {
+	Detector
+	mode Mode
+}
+
+// Redactor applies a Config. It is immutable and safe for concurrent use.
+type Redactor struct {
+	detectors []detector
+	mode      Mode
+	hashKey   []byte
+	sensitive map[string]bool
+}
+
+// New compiles a Config.
+func New(cfg Config) (*Redactor, error) {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeMask
+	}
+	if err := validMode(cfg.Mode); err != nil {
+		return nil, err
+	}
+	r := &Redactor{mode: cfg.Mode, hashKey: []byte(cfg.HashKey), sensitive: map[string]bool{}}
+
+	names := cfg.Detectors
+	if len(names) == 0 {
+		names = DefaultDetectors
+	}
+	for _, name := range names {
+		d, ok := Detectors[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("redact: unknown detector %q", name)
+		}
+		r.detectors = append(r.detectors, detector{Detector: d, mode: cfg.Mode})
+	}
+	for _, rule := range cfg.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact: rule %s: %w", rule.Name, err)
+		}
+		mode := rule.Mode
+		if mode == "" {
+			mode = cfg.Mode
+		}
+		if err := validMode(mode); err != nil {
+			return nil, fmt.Errorf("redact: rule %s: %w", rule.Name, err)
+		}
+		name := rule.Name
+		if name == "" {
+			name = "custom"
+		}
+		r.detectors = append(r.detectors, detector{Detector: Detector{Name: name, Pattern: re}, mode: mode})
+	}
+
+	keys := cfg.SensitiveKeys
+	if keys == nil {
+		keys = DefaultSensitiveKeys
+	}
+	for _, k := range keys {
+		r.sensitive[normalizeKey(k)] = true
+	}
+	return r, nil
+}
+
+func validMode(m Mode) error {
+	switch m {
+	case ModeMask, ModeHash, ModeDrop:
+		return nil
+	}
+	return fmt.Errorf("redact: unknown mode %q", m)
+}
+
+// String redacts free text and returns the result with the number of
+// redactions made.
+func (r *Redactor) String(s string) (string, int) {
+	n := 0
+	for _, d := range r.detectors {
+		if !d.Pattern.MatchString(s) {
+			continue
+		}
+		s = r.replace(s, d, &n)
+	}
+	return s, n
+}
+
+// replace rewrites every validated match of d in s.
+func (r *Redactor) replace(s string, d detector, n *int) string {
+	var b strings.Builder
+	last := 0
+	for _, idx := range d.Pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := idx[0], idx[1]
+		if len(idx) >= 4 && idx[2] >= 0 {
+			start, end = idx[2], idx[3]
+		}
+		match := s[start:end]
+		if d.Validate != nil && !d.Validate(match) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(r.token(d.Name, d.mode, match))
+		last = end
+		*n++
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (r *Redactor) token(name string, mode Mode, value string) string {
+	switch mode {
+	case ModeDrop:
+		return ""
+	case ModeHash:
+		var sum []byte
+		if len(r.hashKey) > 0 {
+			mac := hmac.New(sha256.New, r.hashKey)
+			mac.Write([]byte(value))
+			sum = mac.Sum(nil)
+		} else {
+			h := sha256.Sum256([]byte(value))
+			sum = h[:]
+		}
+		return "[" + name + ":" + hex.EncodeToString(sum[:8]) + "]"
+	}
+	return "[REDACTED:" + name + "]"
+}
+
+// SensitiveKey reports whether a map key's whole value should be redacted.
+func (r *Redactor) SensitiveKey(key string) bool {
+	k := normalizeKey(key)
+	return r.sensitive[k] || strings.Contains(k, "password") || strings.Contains(k, "secret")
+}
+
+func normalizeKey(k string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(k)), "-", "_")
+}
+
+// Apply redacts every exported string reachable from v, which must be a
+// pointer (e.g. *model.LogEntry, *model.MetricPayload, *model.ActionSpec,
+// *model.CommandResult). Map values under sensitive keys are redacted
+// whole. It returns the number of redactions made.
+func (r *Redactor) Apply(v interface{}) int {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return 0
+	}
+	return r.walk(rv.Elem())
+}
+
+func (r *Redactor) walk(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return 0
+		}
+		return r.walk(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		// Values inside an interface are not addressable; redact a copy
+		// and store it back.
+		elem := v.Elem()
+		cp := reflect.New(elem.Type()).Elem()
+		cp.Set(elem)
+		n := r.walk(cp)
+		if n > 0 && v.CanSet() {
+			v.Set(cp)
+		}
+		return n
+	case reflect.Struct:
+		n := 0
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue // unexported, e.g. inside time.Time
+			}
+			n += r.walk(v.Field(i))
+		}
+		return n
+	case reflect.String:
+		if !v.CanSet() {
+			return 0
+		}
+		s, n := r.String(v.String())
+		if n > 0 {
+			v.SetString(s)
+		}
+		return n
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return 0
+		}
+		n := 0
+		for i := 0; i < v.Len(); i++ {
+			n += r.walk(v.Index(i))
+		}
+		return n
+	case reflect.Map:
+		return r.walkMap(v)
+	}
+	return 0
+}
+
+func (r *Redactor) walkMap(m reflect.Value) int {
+	if m.IsNil() {
+		return 0
+	}
+	n := 0
+	for _, key := range m.MapKeys() {
+		val := m.MapIndex(key)
+		if key.Kind() == reflect.String && r.SensitiveKey(key.String()) && !isEmpty(val) {
+			n++
+			if r.mode == ModeDrop {
+				m.SetMapIndex(key, reflect.Value{})
+				continue
+			}
+			if tok, ok := r.wholeValue(val, key.String()); ok {
+				m.SetMapIndex(key, tok)
+				continue
+			}
+		}
+		cp := reflect.New(val.Type()).Elem()
+		cp.Set(val)
+		found := r.walk(cp)
+		if found == 0 {
+			continue
+		}
+		n += found
+		if r.dropsIn(val) {
+			m.SetMapIndex(key, reflect.Value{})
+			continue
+		}
+		m.SetMapIndex(key, cp)
+	}
+	return n
+}
+
+// wholeValue builds the replacement for a sensitive key's value, if the
+// value type can hold a string.
+func (r *Redactor) wholeValue(val reflect.Value, key string) (reflect.Value, bool) {
+	raw := fmt.Sprint(val.Interface())
+	tok := reflect.ValueOf(r.token(normalizeKey(key), r.mode, raw))
+	switch {
+	case val.Kind() == reflect.String:
+		return tok.Convert(val.Type()), true
+	case val.Kind() == reflect.Interface:
+		out := reflect.New(val.Type()).Elem()
+		out.Set(tok)
+		return out, true
+	}
+	return reflect.Value{}, false
+}
+
+// dropsIn reports whether a map entry with a redacted string value should be
+// removed: any detector in drop mode may have matched it.
+func (r *Redactor) dropsIn(val reflect.Value) bool {
+	for val.Kind() == reflect.Interface && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.String {
+		return false
+	}
+	s := val.String()
+	for _, d := range r.detectors {
+		if d.mode == ModeDrop && d.Pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmpty(v reflect.Value) bool {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.String && v.Len() == 0
+}
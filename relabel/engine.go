@@ -0,0 +1,296 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/relabel/engine.go
+
+package relabel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+// Internal labels exposed to rules. Like Prometheus, every label starting with
+// "__" is removed once relabeling finishes, so rules must copy what they need
+// (for example with labelmap regex "__meta_(.+)").
+const (
+	LabelName      = "__name__"      // metric or span name
+	LabelNamespace = "__namespace__" // namespace the item was routed by
+	LabelSource    = "__source__"    // LogEntry.Source
+	LabelLevel     = "__level__"     // LogEntry.Level
+	LabelCategory  = "__category__"  // LogEntry.Category
+	LabelService   = "__service__"   // TraceSpan.ServiceName
+	MetaPrefix     = "__meta_"       // prefix for utils.ExtractStandardLabels output
+)
+
+// namespaceRules holds the compiled rules for one namespace.
+type namespaceRules struct {
+	metrics *Rules
+	logs    *Rules
+	traces  *Rules
+}
+
+// Engine applies a model.RelabelRuleSet to metrics, logs and spans.
+// It is immutable after construction and safe for concurrent use.
+type Engine struct {
+	global     namespaceRules
+	namespaces map[string]namespaceRules
+}
+
+// NewEngine compiles every rule in the set.
+func NewEngine(set model.RelabelRuleSet) (*Engine, error) {
+	e := &Engine{namespaces: make(map[string]namespaceRules)}
+	var global model.NamespaceRelabel
+
+	for _, ns := range set.Namespaces {
+		if ns.Namespace == "" || ns.Namespace == "*" {
+			global.Metrics = append(global.Metrics, ns.Metrics...)
+			global.Logs = append(global.Logs, ns.Logs...)
+			global.Traces = append(global.Traces, ns.Traces...)
+			continue
+		}
+		key := strings.ToLower(ns.Namespace)
+		if _, dup := e.namespaces[key]; dup {
+			return nil, fmt.Errorf("relabel: duplicate namespace %q", ns.Namespace)
+		}
+		compiled, err := compileNamespace(ns)
+		if err != nil {
+			return nil, err
+		}
+		e.namespaces[key] = compiled
+	}
+
+	var err error
+	if e.global, err = compileNamespace(global); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func compileNamespace(ns model.NamespaceRelabel) (namespaceRules, error) {
+	var out namespaceRules
+	var err error
+	if out.metrics, err = Compile(ns.Metrics); err != nil {
+		return out, fmt.Errorf("namespace %q metrics: %w", ns.Namespace, err)
+	}
+	if out.logs, err = Compile(ns.Logs); err != nil {
+		return out, fmt.Errorf("namespace %q logs: %w", ns.Namespace, err)
+	}
+	if out.traces, err = Compile(ns.Traces); err != nil {
+		return out, fmt.Errorf("namespace %q traces: %w", ns.Namespace, err)
+	}
+	return out, nil
+}
+
+// run applies the global rules and then the namespace rules selected by pick.
+func (e *Engine) run(namespace string, labels map[string]string, pick func(namespaceRules) *Rules) (map[string]string, bool) {
+	out, keep := pick(e.global).Process(labels)
+	if !keep {
+		return nil, false
+	}
+	if ns, ok := e.namespaces[strings.ToLower(namespace)]; ok {
+		if out, keep = pick(ns).Process(out); !keep {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// MetaLabels replaces utils.ExtractStandardLabels when relabeling is
+// configured: it extracts the standard labels from meta and runs the metric
+// rules for the namespace over them.
+func (e *Engine) MetaLabels(namespace string, meta *model.Meta) (map[string]string, bool) {
+	labels := utils.ExtractStandardLabels(meta)
+	labels[LabelNamespace] = namespace
+	out, keep := e.run(namespace, labels, func(n namespaceRules) *Rules { return n.metrics })
+	if !keep {
+		return nil, false
+	}
+	return stripInternal(out), true
+}
+
+// RelabelMetric applies the metric rules to every data point of m. Points
+// dropped by a keep/drop rule are removed, and a rewritten __name__ renames
+// the metric. Points renamed to different names are split into separate
+// metrics, one per name in order of first appearance, that share m's other
+// fields. It returns nil if no data points remain.
+func (e *Engine) RelabelMetric(m *model.Metric) []model.Metric {
+	if m == nil {
+		return nil
+	}
+	base := metaLabels(m.Meta)
+	base[LabelName] = m.Name
+	base[LabelNamespace] = m.Namespace
+
+	var out []model.Metric
+	index := map[string]int{} // metric name -> position in out
+	for _, dp := range m.DataPoints {
+		labels := make(map[string]string, len(base)+len(dp.Attributes))
+		for k, v := range base {
+			labels[k] = v
+		}
+		for k, v := range dp.Attributes {
+			labels[k] = v
+		}
+
+		res, keep := e.run(m.Namespace, labels, func(n namespaceRules) *Rules { return n.metrics })
+		if !keep {
+			continue
+		}
+		name := res[LabelName]
+		if name == "" {
+			name = m.Name
+		}
+		dp.Attributes = stripInternal(res)
+
+		i, ok := index[name]
+		if !ok {
+			split := *m
+			split.Name = name
+			split.DataPoints = nil
+			i = len(out)
+			index[name] = i
+			out = append(out, split)
+		}
+		out[i].DataPoints = append(out[i].DataPoints, dp)
+	}
+	return out
+}
+
+// RelabelMetrics applies RelabelMetric to each metric and returns the
+// resulting metrics that still have data points.
+func (e *Engine) RelabelMetrics(metrics []model.Metric) []model.Metric {
+	out := make([]model.Metric, 0, len(metrics))
+	for i := range metrics {
+		out = append(out, e.RelabelMetric(&metrics[i])...)
+	}
+	return out
+}
+
+// RelabelLog applies the log rules to entry.Labels in place. It returns false
+// if the entry should be dropped.
+func (e *Engine) RelabelLog(entry *model.LogEntry) bool {
+	if entry == nil {
+		return false
+	}
+	namespace := namespaceOf(entry.Meta, entry.Labels)
+
+	labels := metaLabels(entry.Meta)
+	for k, v := range entry.Labels {
+		labels[k] = v
+	}
+	labels[LabelNamespace] = namespace
+	labels[LabelSource] = entry.Source
+	labels[LabelLevel] = entry.Level
+	labels[LabelCategory] = entry.Category
+
+	out, keep := e.run(namespace, labels, func(n namespaceRules) *Rules { return n.logs })
+	if !keep {
+		return false
+	}
+	entry.Labels = stripInternal(out)
+	return true
+}
+
+// RelabelLogs applies RelabelLog to each entry and returns the kept entries.
+func (e *Engine) RelabelLogs(entries []model.LogEntry) []model.LogEntry {
+	out := entries[:0]
+	for i := range entries {
+		if e.RelabelLog(&entries[i]) {
+			out = append(out, entries[i])
+		}
+	}
+	return out
+}
+
+// RelabelSpan applies the trace rules to span.Attributes in place. It returns
+// false if the span should be dropped.
+func (e *Engine) RelabelSpan(span *model.TraceSpan) bool {
+	if span == nil {
+		return false
+	}
+	namespace := namespaceOf(span.Meta, span.Attributes)
+
+	labels := metaLabels(span.Meta)
+	for k, v := range span.Attributes {
+		labels[k] = v
+	}
+	labels[LabelNamespace] = namespace
+	labels[LabelName] = span.Name
+	labels[LabelService] = span.ServiceName
+
+	out, keep := e.run(namespace, labels, func(n namespaceRules) *Rules { return n.traces })
+	if !keep {
+		return false
+	}
+	span.Attributes = stripInternal(out)
+	return true
+}
+
+// RelabelSpans applies RelabelSpan to each span and returns the kept spans.
+func (e *Engine) RelabelSpans(spans []model.TraceSpan) []model.TraceSpan {
+	out := spans[:0]
+	for i := range spans {
+		if e.RelabelSpan(&spans[i]) {
+			out = append(out, spans[i])
+		}
+	}
+	return out
+}
+
+// metaLabels exposes the standard meta labels under MetaPrefix.
+func metaLabels(meta *model.Meta) map[string]string {
+	out := make(map[string]string)
+	if meta == nil {
+		return out
+	}
+	for k, v := range utils.ExtractStandardLabels(meta) {
+		out[MetaPrefix+k] = v
+	}
+	return out
+}
+
+// namespaceOf resolves the namespace for logs and spans, which carry it as a
+// label rather than a field.
+func namespaceOf(meta *model.Meta, labels map[string]string) string {
+	if ns := labels["namespace"]; ns != "" {
+		return ns
+	}
+	if meta != nil {
+		if ns := meta.Labels["namespace"]; ns != "" {
+			return ns
+		}
+	}
+	return ""
+}
+
+// stripInternal removes every "__"-prefixed label.
+func stripInternal(labels map[string]string) map[string]string {
+	for k := range labels {
+		if strings.HasPrefix(k, "__") {
+			delete(labels, k)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,232 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/relabel/relabel.go
+
+// Package relabel implements Prometheus-style relabeling over GoSight label
+// maps. Rules are described by model.RelabelConfig and compiled once into
+// Rules, which can then be run over any map[string]string.
+package relabel
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Supported relabel actions.
+const (
+	ActionReplace   = "replace"
+	ActionKeep      = "keep"
+	ActionDrop      = "drop"
+	ActionHashMod   = "hashmod"
+	ActionLabelMap  = "labelmap"
+	ActionLabelDrop = "labeldrop"
+	ActionLabelKeep = "labelkeep"
+	ActionLowercase = "lowercase"
+	ActionUppercase = "uppercase"
+)
+
+const (
+	defaultSeparator   = ";"
+	defaultRegex       = "(.*)"
+	defaultReplacement = "$1"
+)
+
+// targetLabelRe is what a target label must look like after expansion.
+var targetLabelRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*$`)
+
+// rule is a compiled model.RelabelConfig.
+type rule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	modulus      uint64
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// Rules is an ordered, compiled list of relabel rules.
+type Rules struct {
+	rules []rule
+}
+
+// Compile validates and compiles relabel configs, applying Prometheus defaults.
+func Compile(cfgs []model.RelabelConfig) (*Rules, error) {
+	out := &Rules{rules: make([]rule, 0, len(cfgs))}
+	for i, c := range cfgs {
+		r := rule{
+			sourceLabels: c.SourceLabels,
+			separator:    c.Separator,
+			modulus:      c.Modulus,
+			targetLabel:  c.TargetLabel,
+			replacement:  defaultReplacement,
+			action:       strings.ToLower(c.Action),
+		}
+		if r.separator == "" {
+			r.separator = defaultSeparator
+		}
+		if c.Replacement != nil {
+			r.replacement = *c.Replacement
+		}
+		if r.action == "" {
+			r.action = ActionReplace
+		}
+
+		expr := c.Regex
+		if expr == "" {
+			expr = defaultRegex
+		}
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel rule %d: invalid regex %q: %w", i, c.Regex, err)
+		}
+		r.regex = re
+
+		switch r.action {
+		case ActionReplace:
+			if r.targetLabel == "" {
+				return nil, fmt.Errorf("relabel rule %d: action %q requires target_label", i, r.action)
+			}
+		case ActionHashMod:
+			if r.targetLabel == "" || r.modulus == 0 {
+				return nil, fmt.Errorf("relabel rule %d: action %q requires target_label and a non-zero modulus", i, r.action)
+			}
+		case ActionLowercase, ActionUppercase:
+			if r.targetLabel == "" {
+				return nil, fmt.Errorf("relabel rule %d: action %q requires target_label", i, r.action)
+			}
+		case ActionKeep, ActionDrop:
+			if len(r.sourceLabels) == 0 {
+				return nil, fmt.Errorf("relabel rule %d: action %q requires source_labels", i, r.action)
+			}
+		case ActionLabelMap, ActionLabelDrop, ActionLabelKeep:
+		default:
+			return nil, fmt.Errorf("relabel rule %d: unknown action %q", i, c.Action)
+		}
+
+		out.rules = append(out.rules, r)
+	}
+	return out, nil
+}
+
+// Len returns the number of compiled rules.
+func (rs *Rules) Len() int {
+	if rs == nil {
+		return 0
+	}
+	return len(rs.rules)
+}
+
+// Process runs the rules over a copy of labels. It returns the resulting labels
+// and false if a keep/drop rule discarded the item. The input map is not
+// modified.
+func (rs *Rules) Process(labels map[string]string) (map[string]string, bool) {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	if rs == nil {
+		return out, true
+	}
+
+	for _, r := range rs.rules {
+		if !r.apply(out) {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// apply runs one rule over labels in place, returning false to drop.
+func (r *rule) apply(labels map[string]string) bool {
+	vals := make([]string, len(r.sourceLabels))
+	for i, name := range r.sourceLabels {
+		vals[i] = labels[name]
+	}
+	val := strings.Join(vals, r.separator)
+
+	switch r.action {
+	case ActionKeep:
+		return r.regex.MatchString(val)
+
+	case ActionDrop:
+		return !r.regex.MatchString(val)
+
+	case ActionReplace:
+		idx := r.regex.FindStringSubmatchIndex(val)
+		if idx == nil {
+			return true
+		}
+		target := string(r.regex.ExpandString(nil, r.targetLabel, val, idx))
+		if !targetLabelRe.MatchString(target) {
+			return true
+		}
+		res := string(r.regex.ExpandString(nil, r.replacement, val, idx))
+		if res == "" {
+			delete(labels, target)
+		} else {
+			labels[target] = res
+		}
+
+	case ActionLowercase:
+		labels[r.targetLabel] = strings.ToLower(val)
+
+	case ActionUppercase:
+		labels[r.targetLabel] = strings.ToUpper(val)
+
+	case ActionHashMod:
+		sum := md5.Sum([]byte(val))
+		mod := binary.BigEndian.Uint64(sum[8:]) % r.modulus
+		labels[r.targetLabel] = fmt.Sprintf("%d", mod)
+
+	case ActionLabelMap:
+		mapped := make(map[string]string)
+		for name, v := range labels {
+			if r.regex.MatchString(name) {
+				mapped[r.regex.ReplaceAllString(name, r.replacement)] = v
+			}
+		}
+		for name, v := range mapped {
+			labels[name] = v
+		}
+
+	case ActionLabelDrop:
+		for name := range labels {
+			if r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+
+	case ActionLabelKeep:
+		for name := range labels {
+			if !r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
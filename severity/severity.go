@@ -0,0 +1,345 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/severity/severity.go
+
+// Package severity is the canonical log severity scale for GoSight. Levels
+// are OpenTelemetry severity numbers (1-24); conversions exist to and from
+// syslog and journald priorities, Windows event levels, CEF severities and
+// level names, so that LogEntry.Level, SeverityText and SeverityNumber can
+// be kept consistent and compared.
+package severity
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Level is an OpenTelemetry SeverityNumber. Higher is more severe.
+type Level int32
+
+// The first level of each OpenTelemetry range. Each range has four steps,
+// e.g. Info, Info+1 (INFO2), Info+2 (INFO3), Info+3 (INFO4).
+const (
+	Unspecified Level = 0
+	Trace       Level = 1
+	Debug       Level = 5
+	Info        Level = 9
+	Warn        Level = 13
+	Error       Level = 17
+	Fatal       Level = 21
+	maxLevel    Level = 24
+)
+
+// Syslog and journald priorities (RFC 5424 section 6.2.1).
+const (
+	SyslogEmergency = iota
+	SyslogAlert
+	SyslogCritical
+	SyslogError
+	SyslogWarning
+	SyslogNotice
+	SyslogInfo
+	SyslogDebug
+)
+
+// Windows event log levels.
+const (
+	WindowsLogAlways   = 0
+	WindowsCritical    = 1
+	WindowsError       = 2
+	WindowsWarning     = 3
+	WindowsInformation = 4
+	WindowsVerbose     = 5
+)
+
+var rangeNames = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// Valid reports whether l is within 1-24.
+func (l Level) Valid() bool {
+	return l >= Trace && l <= maxLevel
+}
+
+// base returns the first level of l's range.
+func (l Level) base() Level {
+	return (l-1)/4*4 + 1
+}
+
+// String returns the GoSight level name: trace, debug, info, warn, error or
+// fatal, or "" when unspecified. This is the value stored in LogEntry.Level.
+func (l Level) String() string {
+	if !l.Valid() {
+		return ""
+	}
+	return rangeNames[(l-1)/4]
+}
+
+// Text returns the OpenTelemetry short name, e.g. "INFO" or "ERROR2".
+func (l Level) Text() string {
+	if !l.Valid() {
+		return ""
+	}
+	name := strings.ToUpper(l.String())
+	if step := l - l.base(); step > 0 {
+		name += strconv.Itoa(int(step) + 1)
+	}
+	return name
+}
+
+// Number returns the OpenTelemetry severity number.
+func (l Level) Number() int32 {
+	return int32(l)
+}
+
+// Compare returns -1, 0 or 1 as a is less, equally or more severe than b.
+func Compare(a, b Level) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast reports whether l is at least as severe as min. Filters compare
+// whole ranges, so "warn" admits WARN through WARN4 and everything above.
+func (l Level) AtLeast(min Level) bool {
+	if !min.Valid() {
+		return true
+	}
+	return l >= min.base()
+}
+
+// Parse maps a level name to a Level. It accepts the OpenTelemetry short
+// names (TRACE..FATAL4), syslog keywords (emerg, crit, err, notice, ...),
+// and common aliases such as warning, critical, verbose or panic. Matching
+// is case-insensitive.
+func Parse(s string) (Level, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Unspecified, false
+	}
+	// OpenTelemetry names with a step suffix: INFO2, ERROR4, ...
+	if n := len(s); n > 1 && s[n-1] >= '2' && s[n-1] <= '4' {
+		for i, name := range rangeNames {
+			if s[:n-1] == name {
+				return Level(i*4+1) + Level(s[n-1]-'1'), true
+			}
+		}
+	}
+	switch s {
+	case "trace", "trc", "finest", "finer":
+		return Trace, true
+	case "debug", "dbg", "dbug", "fine", "verbose":
+		return Debug, true
+	case "info", "inf", "information", "informational", "log":
+		return Info, true
+	case "notice", "notification":
+		return Info + 1, true
+	case "warn", "warning", "wrn":
+		return Warn, true
+	case "error", "err", "eror", "severe":
+		return Error, true
+	case "crit", "critical", "crt":
+		return Error + 1, true
+	case "alert":
+		return Error + 2, true
+	case "fatal", "ftl", "emerg", "emergency", "panic":
+		return Fatal, true
+	}
+	return Unspecified, false
+}
+
+// FromOTel converts an OpenTelemetry severity number.
+func FromOTel(n int32) Level {
+	l := Level(n)
+	if !l.Valid() {
+		return Unspecified
+	}
+	return l
+}
+
+// FromSyslog converts a syslog severity (0 emergency .. 7 debug).
+func FromSyslog(sev int) Level {
+	switch sev {
+	case SyslogEmergency:
+		return Fatal
+	case SyslogAlert:
+		return Error + 2
+	case SyslogCritical:
+		return Error + 1
+	case SyslogError:
+		return Error
+	case SyslogWarning:
+		return Warn
+	case SyslogNotice:
+		return Info + 1
+	case SyslogInfo:
+		return Info
+	case SyslogDebug:
+		return Debug
+	}
+	return Unspecified
+}
+
+// Syslog converts l to a syslog severity. Trace maps to debug.
+func (l Level) Syslog() int {
+	switch {
+	case l >= Fatal:
+		return SyslogEmergency
+	case l >= Error+2:
+		return SyslogAlert
+	case l == Error+1:
+		return SyslogCritical
+	case l >= Error:
+		return SyslogError
+	case l >= Warn:
+		return SyslogWarning
+	case l >= Info+1:
+		return SyslogNotice
+	case l >= Info:
+		return SyslogInfo
+	}
+	return SyslogDebug
+}
+
+// FromJournald converts a journald PRIORITY, which uses the syslog scale.
+func FromJournald(priority int) Level {
+	return FromSyslog(priority)
+}
+
+// Journald converts l to a journald PRIORITY.
+func (l Level) Journald() int {
+	return l.Syslog()
+}
+
+// FromWindows converts a Windows event level. LogAlways (0) is treated as
+// information.
+func FromWindows(level int) Level {
+	switch level {
+	case WindowsCritical:
+		return Fatal
+	case WindowsError:
+		return Error
+	case WindowsWarning:
+		return Warn
+	case WindowsLogAlways, WindowsInformation:
+		return Info
+	case WindowsVerbose:
+		return Debug
+	}
+	return Unspecified
+}
+
+// Windows converts l to a Windows event level.
+func (l Level) Windows() int {
+	switch {
+	case l >= Fatal:
+		return WindowsCritical
+	case l >= Error:
+		return WindowsError
+	case l >= Warn:
+		return WindowsWarning
+	case l >= Info:
+		return WindowsInformation
+	}
+	return WindowsVerbose
+}
+
+// FromCEF converts a CEF severity: 0-10 or Low, Medium, High, Very-High.
+func FromCEF(s string) (Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return Info, true
+	case "medium":
+		return Warn, true
+	case "high":
+		return Error, true
+	case "very-high", "very high":
+		return Error + 1, true
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 || n > 10 {
+		return Unspecified, false
+	}
+	switch {
+	case n <= 3:
+		return Info, true
+	case n <= 6:
+		return Warn, true
+	case n <= 8:
+		return Error, true
+	}
+	return Error + 1, true
+}
+
+// Of returns the severity of an entry, preferring SeverityNumber, then
+// SeverityText, then Level.
+func Of(e *model.LogEntry) Level {
+	if l := FromOTel(e.SeverityNumber); l.Valid() {
+		return l
+	}
+	if l, ok := Parse(e.SeverityText); ok {
+		return l
+	}
+	l, _ := Parse(e.Level)
+	return l
+}
+
+// Apply sets Level, SeverityText and SeverityNumber on an entry from l.
+func Apply(e *model.LogEntry, l Level) {
+	if !l.Valid() {
+		return
+	}
+	e.SeverityNumber = l.Number()
+	e.SeverityText = l.Text()
+	e.Level = l.String()
+}
+
+// Normalize derives Level from whichever severity field is set (see Of) and
+// fills SeverityNumber and SeverityText when they are missing. An existing
+// SeverityText is kept, as OpenTelemetry treats it as the source's original
+// wording. Entries with no recognizable severity are left as is.
+func Normalize(e *model.LogEntry) {
+	l := Of(e)
+	if !l.Valid() {
+		return
+	}
+	e.Level = l.String()
+	e.SeverityNumber = l.Number()
+	if e.SeverityText == "" {
+		e.SeverityText = l.Text()
+	}
+}
+
+// Meets reports whether an entry is at least as severe as a filter level
+// such as LogFilter.Level ("warning", "error"). An empty or unknown filter
+// level admits everything.
+func Meets(e *model.LogEntry, min string) bool {
+	m, ok := Parse(min)
+	if !ok {
+		return true
+	}
+	return Of(e).AtLeast(m)
+}
@@ -0,0 +1,187 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/syslog/cef.go
+
+package syslog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/severity"
+)
+
+// cefHeader names the seven pipe-separated CEF header fields after "CEF:".
+var cefHeader = []string{"version", "device_vendor", "device_product", "device_version", "signature_id", "name", "severity"}
+
+// parseCEFMessage parses a CEF message that may be wrapped in an RFC 3164 or
+// RFC 5424 syslog header.
+func parseCEFMessage(s string, h *header, fields map[string]string, opts Options, now time.Time) (string, error) {
+	if !strings.HasPrefix(s, "CEF:") {
+		var err error
+		if detect(s, "") == FormatRFC5424 {
+			s, err = parse5424(s, h, fields)
+		} else {
+			s = parse3164(s, h, opts.Location, now)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return parseCEF(s, h, fields)
+}
+
+// parseCEF parses "CEF:Version|Vendor|Product|Version|SignatureID|Name|Severity|Extension".
+// Header fields are stored as "cef.<field>" and extensions under their own
+// keys. The CEF severity replaces the syslog severity and Name becomes the
+// message unless the extension carries a msg.
+func parseCEF(s string, h *header, fields map[string]string) (string, error) {
+	if !strings.HasPrefix(s, "CEF:") {
+		return "", errMalformed("missing CEF: prefix")
+	}
+	s = s[len("CEF:"):]
+
+	values := make([]string, 0, len(cefHeader))
+	var cur strings.Builder
+	i := 0
+	for ; i < len(s) && len(values) < len(cefHeader); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			i++
+			cur.WriteByte(s[i])
+		case s[i] == '|':
+			values = append(values, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	if len(values) < len(cefHeader) {
+		return "", errMalformed("CEF header has %d of %d fields", len(values), len(cefHeader))
+	}
+	for j, name := range cefHeader {
+		fields["cef."+name] = values[j]
+	}
+	for k, v := range parseCEFExtension(s[i:]) {
+		fields[k] = v
+	}
+
+	if l, ok := severity.FromCEF(values[6]); ok {
+		h.priority = withSeverity(h.priority, l.Syslog())
+	}
+	if h.hostname == "" {
+		h.hostname = fields["dvchost"]
+	}
+	if h.appName == "" {
+		h.appName = values[2]
+	}
+	if rt := fields["rt"]; rt != "" {
+		if ts, ok := parseCEFTime(rt); ok {
+			h.timestamp = ts
+		}
+	}
+	if msg := fields["msg"]; msg != "" {
+		return msg, nil
+	}
+	return values[5], nil
+}
+
+// parseCEFExtension parses space-separated key=value pairs whose values may
+// contain unescaped spaces. A value runs until the next " key=".
+func parseCEFExtension(s string) map[string]string {
+	out := map[string]string{}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return out
+	}
+
+	// Positions of unescaped '=' separators.
+	var eqs []int
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '=' {
+			eqs = append(eqs, i)
+		}
+	}
+
+	keyStart := 0
+	for n, eq := range eqs {
+		key := strings.TrimSpace(s[keyStart:eq])
+		end := len(s)
+		if n+1 < len(eqs) {
+			// The next key is the word immediately before the next '='.
+			next := strings.LastIndexByte(s[eq+1:eqs[n+1]], ' ')
+			if next < 0 {
+				// No space: the '=' belongs to this value; merge it.
+				eqs[n+1] = eq
+				continue
+			}
+			end = eq + 1 + next
+		}
+		if key != "" {
+			out[key] = unescapeCEF(s[eq+1 : end])
+		}
+		keyStart = end + 1
+	}
+	return out
+}
+
+func unescapeCEF(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseCEFTime parses the rt/start/end formats: epoch milliseconds or
+// "MMM dd yyyy HH:mm:ss".
+func parseCEFTime(s string) (time.Time, bool) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), true
+	}
+	for _, layout := range []string{"Jan 02 2006 15:04:05.000 MST", "Jan 02 2006 15:04:05 MST", "Jan 02 2006 15:04:05.000", "Jan 02 2006 15:04:05"} {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,86 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/syslog/framing.go
+
+package syslog
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// MaxFrameSize bounds a single octet-counted frame.
+const MaxFrameSize = 1 << 20
+
+// ScanFrames is a bufio.SplitFunc for syslog over a stream. It accepts both
+// octet-counted framing ("LEN SP MSG", RFC 6587 section 3.4.1) and
+// newline-delimited framing, choosing per frame: a frame that starts with a
+// digit is octet-counted, anything else (normally '<') runs to the next LF.
+func ScanFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) && (data[start] == '\n' || data[start] == '\r' || data[start] == ' ') {
+		start++
+	}
+	if start == len(data) {
+		if atEOF {
+			return len(data), nil, nil
+		}
+		return start, nil, nil
+	}
+
+	if c := data[start]; c >= '1' && c <= '9' {
+		digits := start
+		for digits < len(data) && data[digits] >= '0' && data[digits] <= '9' {
+			digits++
+		}
+		switch {
+		case digits == len(data):
+			if atEOF || digits-start > 8 {
+				return 0, nil, errMalformed("octet count without message")
+			}
+			return start, nil, nil
+		case data[digits] == ' ':
+			n, _ := strconv.Atoi(string(data[start:digits]))
+			if n <= 0 || n > MaxFrameSize {
+				return 0, nil, errMalformed("invalid octet count %q", data[start:digits])
+			}
+			end := digits + 1 + n
+			if end > len(data) {
+				if atEOF {
+					return 0, nil, errMalformed("truncated frame: want %d bytes", n)
+				}
+				return start, nil, nil
+			}
+			return end, data[digits+1 : end], nil
+		}
+		// Digits not followed by a space: a PRI-less line such as a bare
+		// timestamp. Fall through to newline framing.
+	}
+
+	if i := bytes.IndexByte(data[start:], '\n'); i >= 0 {
+		return start + i + 1, bytes.TrimRight(data[start:start+i], "\r"), nil
+	}
+	if atEOF {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
@@ -0,0 +1,184 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/syslog/kv.go
+
+package syslog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/severity"
+)
+
+// isKV reports whether s starts with a bare key=value pair, as SonicWall and
+// Fortinet payloads do.
+func isKV(s string) bool {
+	eq := strings.IndexByte(s, '=')
+	if eq <= 0 {
+		return false
+	}
+	for _, c := range s[:eq] {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseKV splits space-separated key=value pairs. Values may be double
+// quoted, in which case they can contain spaces and \" escapes.
+func parseKV(s string) map[string]string {
+	out := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t")
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return out
+		}
+		key := s[:eq]
+		if sp := strings.IndexAny(key, " \t"); sp >= 0 {
+			// Stray word without a value; skip it.
+			s = s[sp:]
+			continue
+		}
+		s = s[eq+1:]
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			val = b.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			val = s[:end]
+			s = s[end:]
+		}
+		out[key] = val
+	}
+}
+
+// parseVendorKV parses a key=value payload, applying SonicWall or Fortinet
+// conventions for timestamp, host, severity and message. The vendor is
+// inferred from the keys when not given.
+func parseVendorKV(s, vendor string, h *header, fields map[string]string, loc *time.Location) string {
+	kv := parseKV(s)
+	for k, v := range kv {
+		fields[k] = v
+	}
+	if vendor == "" {
+		switch {
+		case kv["devid"] != "" || kv["logid"] != "":
+			vendor = VendorFortinet
+		case kv["sn"] != "" && kv["fw"] != "":
+			vendor = VendorSonicWall
+		}
+	}
+
+	switch vendor {
+	case VendorSonicWall:
+		// id=firewall sn=C0EAE4... time="2024-01-02 03:04:05 UTC" fw=1.2.3.4 pri=6 msg="..."
+		h.hostname = firstNonEmpty(kv["fw"], kv["sn"], h.hostname)
+		h.appName = firstNonEmpty(h.appName, kv["id"], VendorSonicWall)
+		if ts, ok := parseSonicWallTime(kv["time"], loc); ok {
+			h.timestamp = ts
+		}
+		if pri, err := strconv.Atoi(kv["pri"]); err == nil && pri >= 0 && pri <= 7 {
+			h.priority = withSeverity(h.priority, pri)
+		}
+		h.msgID = kv["m"]
+	case VendorFortinet:
+		// date=2024-01-02 time=03:04:05 devname="FGT" logid="0100032001" level="notice" eventtime=... msg="..."
+		h.hostname = firstNonEmpty(kv["devname"], kv["devid"], h.hostname)
+		h.appName = firstNonEmpty(h.appName, VendorFortinet)
+		if ts, ok := parseFortinetTime(kv, loc); ok {
+			h.timestamp = ts
+		}
+		if l, ok := severity.Parse(kv["level"]); ok {
+			h.priority = withSeverity(h.priority, l.Syslog())
+		}
+		h.msgID = kv["logid"]
+		if kv["msg"] == "" {
+			return firstNonEmpty(kv["logdesc"], s)
+		}
+	}
+	return firstNonEmpty(kv["msg"], s)
+}
+
+func parseSonicWallTime(s string, loc *time.Location) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	if strings.HasSuffix(s, " UTC") {
+		s, loc = strings.TrimSuffix(s, " UTC"), time.UTC
+	}
+	ts, err := time.ParseInLocation("2006-01-02 15:04:05", s, loc)
+	return ts, err == nil
+}
+
+// parseFortinetTime prefers eventtime, which FortiOS sends in seconds,
+// microseconds or nanoseconds depending on version, then date/time/tz.
+func parseFortinetTime(kv map[string]string, loc *time.Location) (time.Time, bool) {
+	if n, err := strconv.ParseInt(kv["eventtime"], 10, 64); err == nil && n > 0 {
+		switch {
+		case n > 1e17:
+			return time.Unix(0, n).UTC(), true
+		case n > 1e14:
+			return time.UnixMicro(n).UTC(), true
+		case n > 1e11:
+			return time.UnixMilli(n).UTC(), true
+		default:
+			return time.Unix(n, 0).UTC(), true
+		}
+	}
+	if kv["date"] == "" || kv["time"] == "" {
+		return time.Time{}, false
+	}
+	value, layout := kv["date"]+" "+kv["time"], "2006-01-02 15:04:05"
+	if tz := kv["tz"]; tz != "" {
+		value, layout = value+" "+tz, layout+" -0700"
+	}
+	ts, err := time.ParseInLocation(layout, value, loc)
+	return ts, err == nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,601 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/syslog/listener.go
+
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/utils"
+)
+
+// DefaultPort is used for devices without a Port.
+const DefaultPort = 514
+
+// ListenerConfig configures a Listener.
+type ListenerConfig struct {
+	// Devices are the registered appliances. Devices with Status "disabled"
+	// are ignored. Devices sharing a protocol and port share one socket.
+	Devices []model.NetworkDevice
+
+	// BindAddress is the local address to bind, e.g. "0.0.0.0". Empty binds
+	// all interfaces.
+	BindAddress string
+
+	// TLSConfig is required when any device uses Protocol "tls".
+	TLSConfig *tls.Config
+
+	// Handler receives batches of parsed logs, one payload per device.
+	Handler func(model.LogPayload)
+
+	// BatchSize flushes a device's batch once it holds this many entries.
+	// Defaults to 100.
+	BatchSize int
+
+	// FlushInterval flushes partial batches. Defaults to one second.
+	FlushInterval time.Duration
+
+	// AcceptUnknown emits messages that match no device instead of dropping
+	// them. They are attributed to their source address.
+	AcceptUnknown bool
+
+	// Location is used for timestamps that carry no zone. Defaults to UTC.
+	Location *time.Location
+}
+
+// DeviceStats counts messages per device.
+type DeviceStats struct {
+	Received    uint64 `json:"received"`
+	RateLimited uint64 `json:"rate_limited"`
+	ParseErrors uint64 `json:"parse_errors"`
+}
+
+// Listener receives syslog from registered network devices over UDP, TCP
+// and TLS and hands parsed logs to the configured Handler.
+type Listener struct {
+	cfg    ListenerConfig
+	groups []*socketGroup
+
+	mu      sync.Mutex
+	batches map[string]*model.LogPayload
+	stats   map[string]*DeviceStats
+	closers []func() error
+	conns   map[net.Conn]struct{}
+	addrs   []net.Addr
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// socketGroup is the set of devices served by one socket.
+type socketGroup struct {
+	protocol string // udp, tcp or tls
+	port     int
+	devices  []*device
+}
+
+type device struct {
+	dev     model.NetworkDevice
+	ips     map[string]bool
+	opts    Options
+	limiter *tokenBucket
+	meta    *model.Meta
+}
+
+// NewListener validates the device set and prepares sockets. Nothing is
+// bound until Start is called.
+func NewListener(cfg ListenerConfig) (*Listener, error) {
+	if cfg.Handler == nil {
+		return nil, errors.New("syslog: listener requires a Handler")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+
+	l := &Listener{
+		cfg:     cfg,
+		batches: map[string]*model.LogPayload{},
+		stats:   map[string]*DeviceStats{},
+		conns:   map[net.Conn]struct{}{},
+		done:    make(chan struct{}),
+	}
+
+	index := map[string]*socketGroup{}
+	for i := range cfg.Devices {
+		nd := cfg.Devices[i]
+		if strings.EqualFold(nd.Status, "disabled") {
+			continue
+		}
+		protocol := strings.ToLower(strings.TrimSpace(nd.Protocol))
+		switch protocol {
+		case "":
+			protocol = "udp"
+		case "udp", "tcp":
+		case "tls":
+			if cfg.TLSConfig == nil {
+				return nil, fmt.Errorf("syslog: device %s uses tls but no TLSConfig was given", nd.ID)
+			}
+		default:
+			return nil, fmt.Errorf("syslog: device %s has unsupported protocol %q", nd.ID, nd.Protocol)
+		}
+		port := nd.Port
+		if port == 0 {
+			port = DefaultPort
+		}
+
+		key := protocol + "/" + strconv.Itoa(port)
+		g, ok := index[key]
+		if !ok {
+			g = &socketGroup{protocol: protocol, port: port}
+			index[key] = g
+			l.groups = append(l.groups, g)
+		}
+
+		d := &device{
+			dev:     nd,
+			ips:     resolve(nd.Address),
+			opts:    OptionsFor(&nd),
+			limiter: newTokenBucket(nd.RateLimit),
+			meta:    deviceMeta(&nd),
+		}
+		d.opts.Location = cfg.Location
+		g.devices = append(g.devices, d)
+		l.stats[nd.ID] = &DeviceStats{}
+	}
+	return l, nil
+}
+
+// deviceMeta describes a device as a "syslog" resource.
+func deviceMeta(nd *model.NetworkDevice) *model.Meta {
+	return &model.Meta{
+		Kind:       "syslog",
+		EndpointID: "syslog-" + nd.ID,
+		Hostname:   firstNonEmpty(nd.Name, nd.Address),
+		IPAddress:  nd.Address,
+		Platform:   nd.Vendor,
+		Labels: map[string]string{
+			"device_id":   nd.ID,
+			"device_name": nd.Name,
+			"vendor":      nd.Vendor,
+		},
+	}
+}
+
+// resolve returns the IPs for a device address. Unresolvable hostnames are
+// logged and left to SyslogID attribution.
+func resolve(addr string) map[string]bool {
+	out := map[string]bool{}
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return out
+	}
+	if ip := net.ParseIP(addr); ip != nil {
+		out[ip.String()] = true
+		return out
+	}
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		utils.Warn("syslog: cannot resolve device address %s: %v", addr, err)
+		return out
+	}
+	for _, ip := range ips {
+		out[ip.String()] = true
+	}
+	return out
+}
+
+// Start binds every socket and begins serving until ctx is cancelled or
+// Close is called. If any socket fails to bind, all are closed and the
+// error is returned.
+func (l *Listener) Start(ctx context.Context) error {
+	for _, g := range l.groups {
+		addr := net.JoinHostPort(l.cfg.BindAddress, strconv.Itoa(g.port))
+		if err := l.bind(g, addr); err != nil {
+			l.Close()
+			return fmt.Errorf("syslog: listen %s %s: %w", g.protocol, addr, err)
+		}
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(l.cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.Flush()
+			case <-ctx.Done():
+				go l.Close()
+				return
+			case <-l.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (l *Listener) bind(g *socketGroup, addr string) error {
+	if g.protocol == "udp" {
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return err
+		}
+		l.track(pc.Close, pc.LocalAddr())
+		l.wg.Add(1)
+		go l.serveUDP(g, pc)
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if g.protocol == "tls" {
+		ln = tls.NewListener(ln, l.cfg.TLSConfig)
+	}
+	l.track(ln.Close, ln.Addr())
+	l.wg.Add(1)
+	go l.serveTCP(g, ln)
+	return nil
+}
+
+func (l *Listener) track(closer func() error, addr net.Addr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closers = append(l.closers, closer)
+	l.addrs = append(l.addrs, addr)
+}
+
+// Addrs returns the bound local addresses.
+func (l *Listener) Addrs() []net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]net.Addr(nil), l.addrs...)
+}
+
+func (l *Listener) serveUDP(g *socketGroup, pc net.PacketConn) {
+	defer l.wg.Done()
+	buf := make([]byte, 64*1024)
+	for {
+		n, src, err := pc.ReadFrom(buf)
+		if err != nil {
+			if !l.isClosed() {
+				utils.Error("syslog: udp read on port %d: %v", g.port, err)
+			}
+			return
+		}
+		l.handle(g, src, buf[:n])
+	}
+}
+
+func (l *Listener) serveTCP(g *socketGroup, ln net.Listener) {
+	defer l.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !l.isClosed() {
+				utils.Error("syslog: accept on port %d: %v", g.port, err)
+			}
+			return
+		}
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			conn.Close()
+			return
+		}
+		l.conns[conn] = struct{}{}
+		l.mu.Unlock()
+
+		l.wg.Add(1)
+		go l.serveConn(g, conn)
+	}
+}
+
+func (l *Listener) serveConn(g *socketGroup, conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+	}()
+
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 0, 64*1024), MaxFrameSize+16)
+	sc.Split(ScanFrames)
+	for sc.Scan() {
+		l.handle(g, conn.RemoteAddr(), sc.Bytes())
+	}
+	if err := sc.Err(); err != nil && !l.isClosed() {
+		utils.Warn("syslog: connection from %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// handle attributes, rate limits, parses and batches one message.
+func (l *Listener) handle(g *socketGroup, src net.Addr, raw []byte) {
+	ip := hostOf(src)
+	now := time.Now()
+
+	// Attribute by source address first; only parse before rate limiting
+	// when SyslogID is needed to pick the device.
+	candidates := g.byAddress(ip)
+	var (
+		dev   *device
+		entry *model.LogEntry
+		err   error
+	)
+	if len(candidates) == 1 {
+		dev = candidates[0]
+		if !l.allow(dev, now) {
+			return
+		}
+		entry, err = Parse(raw, dev.opts)
+	} else {
+		pool := candidates
+		if len(pool) == 0 {
+			pool = g.devices
+		}
+		entry, err = Parse(raw, Options{Location: l.cfg.Location})
+		if err == nil {
+			dev = bySyslogID(pool, entry)
+		}
+		if dev != nil {
+			if !l.allow(dev, now) {
+				return
+			}
+			if dev.opts.Format != FormatAuto || dev.opts.Vendor != "" {
+				entry, err = Parse(raw, dev.opts)
+			}
+		}
+	}
+
+	id := ""
+	if dev != nil {
+		id = dev.dev.ID
+	}
+	l.mu.Lock()
+	st := l.statsFor(id)
+	st.Received++
+	if err != nil {
+		st.ParseErrors++
+		l.mu.Unlock()
+		utils.Debug("syslog: parse error from %s: %v", ip, err)
+		return
+	}
+	l.mu.Unlock()
+
+	if dev == nil {
+		if !l.cfg.AcceptUnknown {
+			return
+		}
+		l.emit("unknown-"+ip, &model.Meta{Kind: "syslog", Hostname: ip, IPAddress: ip}, entry)
+		return
+	}
+	entry.Meta = dev.meta
+	l.emit(id, dev.meta, entry)
+}
+
+func (l *Listener) allow(d *device, now time.Time) bool {
+	if d.limiter.allow(now) {
+		return true
+	}
+	l.mu.Lock()
+	st := l.statsFor(d.dev.ID)
+	st.Received++
+	st.RateLimited++
+	l.mu.Unlock()
+	return false
+}
+
+// statsFor must be called with l.mu held.
+func (l *Listener) statsFor(id string) *DeviceStats {
+	st, ok := l.stats[id]
+	if !ok {
+		st = &DeviceStats{}
+		l.stats[id] = st
+	}
+	return st
+}
+
+// emit appends an entry to its device batch, flushing when full.
+func (l *Listener) emit(key string, meta *model.Meta, entry *model.LogEntry) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		b = &model.LogPayload{
+			EndpointID: meta.EndpointID,
+			Hostname:   meta.Hostname,
+			Meta:       meta,
+		}
+		l.batches[key] = b
+	}
+	b.Logs = append(b.Logs, *entry)
+	var full *model.LogPayload
+	if len(b.Logs) >= l.cfg.BatchSize {
+		full = b
+		delete(l.batches, key)
+	}
+	l.mu.Unlock()
+
+	if full != nil {
+		full.Timestamp = time.Now()
+		l.cfg.Handler(*full)
+	}
+}
+
+// Flush hands every partial batch to the Handler.
+func (l *Listener) Flush() {
+	l.mu.Lock()
+	pending := make([]*model.LogPayload, 0, len(l.batches))
+	for key, b := range l.batches {
+		pending = append(pending, b)
+		delete(l.batches, key)
+	}
+	l.mu.Unlock()
+
+	for _, b := range pending {
+		b.Timestamp = time.Now()
+		l.cfg.Handler(*b)
+	}
+}
+
+// Stats returns a snapshot of per-device counters keyed by device ID.
+// Messages that matched no device are counted under "".
+func (l *Listener) Stats() map[string]DeviceStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]DeviceStats, len(l.stats))
+	for id, st := range l.stats {
+		out[id] = *st
+	}
+	return out
+}
+
+// Close stops all sockets, waits for in-flight messages and flushes what
+// remains.
+func (l *Listener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.done)
+	closers := l.closers
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+
+	var firstErr error
+	for _, c := range closers {
+		if err := c(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	l.wg.Wait()
+	l.Flush()
+	return firstErr
+}
+
+func (l *Listener) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+// byAddress returns the devices registered for a source IP.
+func (g *socketGroup) byAddress(ip string) []*device {
+	var out []*device
+	for _, d := range g.devices {
+		if d.ips[ip] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// bySyslogID matches a parsed entry's hostname or app name against each
+// device's SyslogID.
+func bySyslogID(pool []*device, e *model.LogEntry) *device {
+	for _, d := range pool {
+		id := d.dev.SyslogID
+		if id == "" {
+			continue
+		}
+		if strings.EqualFold(id, e.Fields["hostname"]) || strings.EqualFold(id, e.Fields["app_name"]) {
+			return d
+		}
+	}
+	return nil
+}
+
+func hostOf(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.String()
+	case *net.TCPAddr:
+		return a.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// tokenBucket allows rate events per second with a burst of the same size.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns nil, which allows everything, when perSec <= 0.
+func newTokenBucket(perSec int) *tokenBucket {
+	if perSec <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: float64(perSec), tokens: float64(perSec)}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,124 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/syslog/rfc3164.go
+
+package syslog
+
+import (
+	"strings"
+	"time"
+)
+
+// bsdLayouts are the timestamp layouts seen in RFC 3164 style messages,
+// longest first. Layouts without a year are completed with the current one.
+var bsdLayouts = []struct {
+	layout string
+	noYear bool
+}{
+	{"Jan _2 2006 15:04:05", false},
+	{"Jan _2 15:04:05.000000", true},
+	{"Jan _2 15:04:05.000", true},
+	{"Jan _2 15:04:05", true},
+}
+
+// parse3164 parses "TIMESTAMP HOSTNAME TAG[PID]: MSG". Every part is
+// optional; whatever cannot be recognised is left in the returned message.
+func parse3164(s string, h *header, loc *time.Location, now time.Time) string {
+	s = strings.TrimLeft(s, " ")
+
+	if ts, rest, ok := parseBSDTimestamp(s, loc, now); ok {
+		h.timestamp = ts
+		s = rest
+	} else if tok, rest := nextToken(s); len(tok) >= 19 && tok[4] == '-' && tok[10] == 'T' {
+		// rsyslog's high-precision RFC 3339 template.
+		if ts, err := time.Parse(time.RFC3339Nano, tok); err == nil {
+			h.timestamp = ts
+			s = rest
+		}
+	}
+
+	// The next token is the hostname unless it already looks like a tag.
+	if tok, rest := nextToken(s); tok != "" && !looksLikeTag(tok) && rest != "" {
+		if next, _ := nextToken(rest); !h.timestamp.IsZero() || looksLikeTag(next) {
+			h.hostname = tok
+			s = rest
+		}
+	}
+
+	if tok, rest := nextToken(s); looksLikeTag(tok) {
+		tag := strings.TrimSuffix(tok, ":")
+		if i := strings.IndexByte(tag, '['); i > 0 && strings.HasSuffix(tag, "]") {
+			h.procID = tag[i+1 : len(tag)-1]
+			tag = tag[:i]
+		}
+		h.appName = tag
+		s = rest
+	}
+	return s
+}
+
+func parseBSDTimestamp(s string, loc *time.Location, now time.Time) (time.Time, string, bool) {
+	for _, l := range bsdLayouts {
+		if len(s) < len(l.layout) {
+			continue
+		}
+		ts, err := time.ParseInLocation(l.layout, s[:len(l.layout)], loc)
+		if err != nil {
+			continue
+		}
+		if l.noYear {
+			ts = ts.AddDate(now.In(loc).Year(), 0, 0)
+			// A December message received in January belongs to last year.
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+		}
+		return ts, strings.TrimLeft(s[len(l.layout):], " "), true
+	}
+	return time.Time{}, s, false
+}
+
+// looksLikeTag reports whether tok is an RFC 3164 TAG such as "sshd:" or
+// "sshd[123]:".
+func looksLikeTag(tok string) bool {
+	if !strings.HasSuffix(tok, ":") || len(tok) < 2 || len(tok) > 64 {
+		return false
+	}
+	for _, c := range tok[:len(tok)-1] {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == '/' || c == '[' || c == ']' || c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// nextToken splits off the first space-delimited token.
+func nextToken(s string) (string, string) {
+	s = strings.TrimLeft(s, " ")
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], strings.TrimLeft(s[i+1:], " ")
+	}
+	return s, ""
+}
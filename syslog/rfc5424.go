@@ -0,0 +1,129 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/syslog/rfc5424.go
+
+package syslog
+
+import (
+	"strings"
+	"time"
+)
+
+// parse5424 parses "VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG".
+// Structured data parameters are stored in fields as "<sd-id>.<param>".
+func parse5424(s string, h *header, fields map[string]string) (string, error) {
+	parts := make([]string, 6)
+	for i := range parts {
+		var tok string
+		tok, s = splitSP(s)
+		if tok == "" {
+			return "", errMalformed("rfc5424 header has %d of 6 fields", i)
+		}
+		parts[i] = tok
+	}
+	if parts[0] != "1" {
+		return "", errMalformed("unsupported rfc5424 version %q", parts[0])
+	}
+	if parts[1] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, parts[1])
+		if err != nil {
+			return "", errMalformed("rfc5424 timestamp %q", parts[1])
+		}
+		h.timestamp = ts
+	}
+	h.hostname = nilValue(parts[2])
+	h.appName = nilValue(parts[3])
+	h.procID = nilValue(parts[4])
+	h.msgID = nilValue(parts[5])
+
+	msg, err := parseStructuredData(s, fields)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(msg, "\ufeff"), nil
+}
+
+// parseStructuredData consumes "-" or one or more "[id param="value" ...]"
+// elements and returns the remaining message.
+func parseStructuredData(s string, fields map[string]string) (string, error) {
+	if s == "-" || strings.HasPrefix(s, "- ") {
+		return strings.TrimPrefix(s[1:], " "), nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		// Tolerate senders that omit the SD field entirely.
+		return s, nil
+	}
+	for strings.HasPrefix(s, "[") {
+		i := 1
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		id := s[1:i]
+		if id == "" || i == len(s) {
+			return "", errMalformed("structured data element")
+		}
+		for i < len(s) && s[i] == ' ' {
+			i++
+			start := i
+			for i < len(s) && s[i] != '=' {
+				i++
+			}
+			if i+1 >= len(s) || s[i+1] != '"' {
+				return "", errMalformed("structured data param in [%s]", id)
+			}
+			name := s[start:i]
+			i += 2
+			var val strings.Builder
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+					i++
+				}
+				val.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return "", errMalformed("unterminated structured data value in [%s]", id)
+			}
+			fields[id+"."+name] = val.String()
+			i++ // closing quote
+		}
+		if i >= len(s) || s[i] != ']' {
+			return "", errMalformed("unterminated structured data element [%s]", id)
+		}
+		s = s[i+1:]
+	}
+	return strings.TrimPrefix(s, " "), nil
+}
+
+// splitSP splits on exactly one space, as RFC 5424 header fields are.
+func splitSP(s string) (string, string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,260 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/syslog/syslog.go
+
+// Package syslog parses syslog messages from network appliances into
+// model.LogEntry values. It understands RFC 3164 (BSD), RFC 5424 and ArcSight
+// CEF, the octet-counted framing used over TCP, and the bare key=value
+// payloads sent by SonicWall and Fortinet firewalls. Listener binds the
+// sockets for a set of model.NetworkDevice records and attributes each
+// message to its device.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+	"github.com/aaronlmathis/gosight-shared/severity"
+)
+
+// Supported message formats, matching model.NetworkDevice.Format.
+const (
+	FormatAuto    = ""
+	FormatRFC3164 = "rfc3164"
+	FormatRFC5424 = "rfc5424"
+	FormatCEF     = "cef"
+)
+
+// Vendors with non-standard payloads.
+const (
+	VendorSonicWall = "sonicwall"
+	VendorFortinet  = "fortinet"
+)
+
+// Source is set on every parsed LogEntry.
+const Source = "syslog"
+
+var (
+	ErrEmptyMessage = errors.New("syslog: empty message")
+	ErrMalformed    = errors.New("syslog: malformed message")
+)
+
+// Options controls how a message is parsed.
+type Options struct {
+	// Format is one of the Format constants. FormatAuto detects the format
+	// from the message itself.
+	Format string
+
+	// Vendor enables vendor-specific key=value parsing ("sonicwall",
+	// "fortinet"). Key=value payloads are also detected without it.
+	Vendor string
+
+	// Location is used for timestamps that carry no zone. Defaults to UTC.
+	Location *time.Location
+
+	// Now returns the receive time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// OptionsFor returns parse options for a registered network device.
+func OptionsFor(dev *model.NetworkDevice) Options {
+	return Options{
+		Format: strings.ToLower(strings.TrimSpace(dev.Format)),
+		Vendor: strings.ToLower(strings.TrimSpace(dev.Vendor)),
+	}
+}
+
+// header holds the fields common to every format.
+type header struct {
+	priority  int // -1 when absent
+	timestamp time.Time
+	hostname  string
+	appName   string
+	procID    string
+	msgID     string
+}
+
+// Parse parses one syslog message. Trailing newlines are ignored.
+func Parse(raw []byte, opts Options) (*model.LogEntry, error) {
+	raw = bytes.TrimRight(raw, "\r\n\x00")
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	now := opts.Now()
+
+	entry := &model.LogEntry{
+		ObservedTimestamp: now,
+		Body:              string(raw),
+		Source:            Source,
+		Fields:            map[string]string{},
+	}
+
+	pri, rest := parsePriority(string(raw))
+	h := header{priority: pri}
+
+	format := opts.Format
+	if format == FormatAuto {
+		format = detect(rest, opts.Vendor)
+	}
+
+	var (
+		msg string
+		err error
+	)
+	switch format {
+	case FormatRFC5424:
+		msg, err = parse5424(rest, &h, entry.Fields)
+	case FormatCEF:
+		msg, err = parseCEFMessage(rest, &h, entry.Fields, opts, now)
+	case VendorSonicWall, VendorFortinet, "kv":
+		msg = parseVendorKV(rest, opts.Vendor, &h, entry.Fields, opts.Location)
+	default:
+		msg = parse3164(rest, &h, opts.Location, now)
+		if strings.HasPrefix(msg, "CEF:") {
+			msg, err = parseCEF(msg, &h, entry.Fields)
+		} else if isKV(msg) {
+			msg = parseVendorKV(msg, opts.Vendor, &h, entry.Fields, opts.Location)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if h.priority < 0 {
+		// RFC 3164 section 4.3.3: a missing PRI is treated as user.notice.
+		h.priority = 13
+	}
+	entry.Timestamp = h.timestamp
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = now
+	}
+	entry.Message = msg
+	applySeverity(entry, h.priority%8)
+
+	facility := h.priority / 8
+	entry.Fields["facility"] = FacilityName(facility)
+	entry.Fields["facility_code"] = strconv.Itoa(facility)
+	setField(entry.Fields, "hostname", h.hostname)
+	setField(entry.Fields, "app_name", h.appName)
+	setField(entry.Fields, "proc_id", h.procID)
+	setField(entry.Fields, "msg_id", h.msgID)
+	if pid, err := strconv.Atoi(h.procID); err == nil {
+		entry.PID = pid
+	}
+	return entry, nil
+}
+
+// detect guesses the format of the message following the PRI.
+func detect(rest, vendor string) string {
+	switch {
+	case len(rest) > 2 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ':
+		return FormatRFC5424
+	case strings.HasPrefix(rest, "CEF:"):
+		return FormatCEF
+	case vendor == VendorSonicWall || vendor == VendorFortinet:
+		if isKV(rest) {
+			return vendor
+		}
+	case isKV(rest):
+		return "kv"
+	}
+	return FormatRFC3164
+}
+
+// parsePriority strips a leading "<PRI>" and returns it, or -1 if absent.
+func parsePriority(s string) (int, string) {
+	if len(s) < 3 || s[0] != '<' {
+		return -1, s
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return -1, s
+	}
+	pri, err := strconv.Atoi(s[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return -1, s
+	}
+	return pri, s[end+1:]
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// FacilityName returns the keyword for a facility code, e.g. 16 -> "local0".
+func FacilityName(code int) string {
+	if code >= 0 && code < len(facilityNames) {
+		return facilityNames[code]
+	}
+	return strconv.Itoa(code)
+}
+
+// FacilityCode returns the code for a facility keyword, or -1 if unknown.
+func FacilityCode(name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, n := range facilityNames {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+var severityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// applySeverity sets Level, SeverityText and SeverityNumber for a syslog
+// severity and records the keyword in Fields.
+func applySeverity(e *model.LogEntry, sev int) {
+	severity.Apply(e, severity.FromSyslog(sev))
+	e.Fields["severity"] = severityNames[sev]
+}
+
+func setField(fields map[string]string, k, v string) {
+	if v != "" && v != "-" {
+		fields[k] = v
+	}
+}
+
+// withSeverity replaces the severity part of a priority, keeping the facility.
+func withSeverity(pri, sev int) int {
+	if pri < 0 {
+		pri = 8 // user
+	}
+	return pri/8*8 + sev
+}
+
+func errMalformed(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrMalformed, fmt.Sprintf(format, a...))
+}
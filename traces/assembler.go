@@ -0,0 +1,156 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/traces/assembler.go
+
+package traces
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// AssemblerConfig controls span buffering.
+type AssemblerConfig struct {
+	// Timeout is how long a trace may go without new spans before it is
+	// considered complete. Default 30s.
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// MaxTraces bounds memory: when exceeded, the least recently updated
+	// trace is completed early. 0 = unlimited.
+	MaxTraces int `yaml:"max_traces,omitempty" json:"max_traces,omitempty"`
+
+	// MaxSpansPerTrace completes a trace as soon as it holds this many
+	// spans. 0 = unlimited.
+	MaxSpansPerTrace int `yaml:"max_spans_per_trace,omitempty" json:"max_spans_per_trace,omitempty"`
+}
+
+// Assembler buffers spans by trace ID and releases assembled traces once
+// they are idle. It is safe for concurrent use.
+type Assembler struct {
+	cfg AssemblerConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*pendingTrace
+}
+
+type pendingTrace struct {
+	spans    []model.TraceSpan
+	lastSeen time.Time
+}
+
+// NewAssembler creates an Assembler.
+func NewAssembler(cfg AssemblerConfig) *Assembler {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &Assembler{cfg: cfg, now: time.Now, pending: map[string]*pendingTrace{}}
+}
+
+// Add buffers a span and returns any traces completed early because of the
+// memory limits.
+func (a *Assembler) Add(span model.TraceSpan) []*Trace {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	p := a.pending[span.TraceID]
+	if p == nil {
+		p = &pendingTrace{}
+		a.pending[span.TraceID] = p
+	}
+	p.spans = append(p.spans, span)
+	p.lastSeen = now
+
+	var done []*Trace
+	if a.cfg.MaxSpansPerTrace > 0 && len(p.spans) >= a.cfg.MaxSpansPerTrace {
+		done = append(done, a.release(span.TraceID))
+	}
+	for a.cfg.MaxTraces > 0 && len(a.pending) > a.cfg.MaxTraces {
+		done = append(done, a.release(a.oldest()))
+	}
+	return done
+}
+
+// AddPayload buffers every span of a payload. Spans without Meta inherit
+// the payload's.
+func (a *Assembler) AddPayload(p *model.TracePayload) []*Trace {
+	var done []*Trace
+	for _, s := range p.Traces {
+		if s.Meta == nil {
+			s.Meta = p.Meta
+		}
+		done = append(done, a.Add(s)...)
+	}
+	return done
+}
+
+// Flush returns traces that have received no spans for at least Timeout.
+func (a *Assembler) Flush() []*Trace {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	var done []*Trace
+	for id, p := range a.pending {
+		if now.Sub(p.lastSeen) >= a.cfg.Timeout {
+			done = append(done, a.release(id))
+		}
+	}
+	return done
+}
+
+// FlushAll returns every buffered trace, e.g. on shutdown.
+func (a *Assembler) FlushAll() []*Trace {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	done := make([]*Trace, 0, len(a.pending))
+	for id := range a.pending {
+		done = append(done, a.release(id))
+	}
+	return done
+}
+
+// Pending returns the number of traces being buffered.
+func (a *Assembler) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.pending)
+}
+
+func (a *Assembler) release(id string) *Trace {
+	p := a.pending[id]
+	delete(a.pending, id)
+	return Build(p.spans)
+}
+
+func (a *Assembler) oldest() string {
+	var id string
+	var oldest time.Time
+	for k, p := range a.pending {
+		if id == "" || p.lastSeen.Before(oldest) {
+			id, oldest = k, p.lastSeen
+		}
+	}
+	return id
+}
@@ -0,0 +1,304 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/traces/servicegraph.go
+
+package traces
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/histogram"
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Connection types of a model.ServiceEdge.
+const (
+	ConnectionDatabase  = "database"
+	ConnectionMessaging = "messaging"
+	ConnectionVirtual   = "virtual"
+)
+
+// DefaultLatencyBounds are the histogram bounds, in milliseconds, used for
+// edge and span latencies.
+var DefaultLatencyBounds = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// GraphConfig controls a GraphBuilder.
+type GraphConfig struct {
+	Window        time.Duration `yaml:"window,omitempty" json:"window,omitempty"`   // default 1m
+	Windows       int           `yaml:"windows,omitempty" json:"windows,omitempty"` // windows of history kept; default 60
+	LatencyBounds []float64     `yaml:"latency_bounds,omitempty" json:"latency_bounds,omitempty"`
+}
+
+// Call is one caller -> callee interaction found in a trace.
+type Call struct {
+	Source         string
+	Target         string
+	ConnectionType string
+	Virtual        bool // the target reports no spans of its own
+	DurationMs     float64
+	Error          bool
+	Span           *model.TraceSpan // the span measured: the callee's server span or the caller's client span
+}
+
+// Calls derives the service calls in a trace. A span whose parent belongs
+// to another service is a call from the parent's service; a client or
+// producer span with no child in another service is a call to the service
+// named by its peer.service, db.system, messaging.system or server address
+// attributes.
+func Calls(t *Trace) []Call {
+	var out []Call
+	t.Walk(func(n *Node) {
+		svc := ServiceName(n.Span)
+		if n.Parent != nil {
+			if parent := ServiceName(n.Parent.Span); parent != svc {
+				out = append(out, Call{
+					Source: parent, Target: svc,
+					DurationMs: DurationMs(n.Span), Error: IsError(n.Span), Span: n.Span,
+				})
+			}
+		}
+		switch n.Span.Attributes[AttrSpanKind] {
+		case "client", "producer":
+		default:
+			return
+		}
+		for _, c := range n.Children {
+			if ServiceName(c.Span) != svc {
+				return // the callee is instrumented and counted above
+			}
+		}
+		target, conn := peerService(n.Span)
+		if target == "" || target == svc {
+			return
+		}
+		out = append(out, Call{
+			Source: svc, Target: target, ConnectionType: conn, Virtual: true,
+			DurationMs: DurationMs(n.Span), Error: IsError(n.Span), Span: n.Span,
+		})
+	})
+	return out
+}
+
+// peerService names the remote side of a client span.
+func peerService(s *model.TraceSpan) (string, string) {
+	a := s.Attributes
+	if v := a["peer.service"]; v != "" {
+		return v, ConnectionVirtual
+	}
+	if v := a["db.system"]; v != "" {
+		if name := a["db.name"]; name != "" {
+			return v + "/" + name, ConnectionDatabase
+		}
+		return v, ConnectionDatabase
+	}
+	if v := a["messaging.system"]; v != "" {
+		if dest := a["messaging.destination.name"]; dest != "" {
+			return v + "/" + dest, ConnectionMessaging
+		}
+		return v, ConnectionMessaging
+	}
+	for _, k := range []string{"server.address", "net.peer.name", "http.host"} {
+		if v := a[k]; v != "" {
+			return v, ConnectionVirtual
+		}
+	}
+	return "", ""
+}
+
+// GraphBuilder aggregates calls from assembled traces into per-window
+// service graphs. It is safe for concurrent use.
+type GraphBuilder struct {
+	cfg GraphConfig
+
+	mu      sync.Mutex
+	windows map[int64]*graphWindow
+	newest  int64
+}
+
+type edgeKey struct{ source, target string }
+
+type graphWindow struct {
+	edges   map[edgeKey]*model.ServiceEdge
+	virtual map[string]bool
+}
+
+// NewGraphBuilder creates a GraphBuilder.
+func NewGraphBuilder(cfg GraphConfig) *GraphBuilder {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Windows <= 0 {
+		cfg.Windows = 60
+	}
+	if len(cfg.LatencyBounds) == 0 {
+		cfg.LatencyBounds = DefaultLatencyBounds
+	}
+	cfg.LatencyBounds = append([]float64(nil), cfg.LatencyBounds...)
+	return &GraphBuilder{cfg: cfg, windows: map[int64]*graphWindow{}}
+}
+
+// AddTrace records a trace's calls in the window of its start time.
+func (g *GraphBuilder) AddTrace(t *Trace) {
+	calls := Calls(t)
+	if len(calls) == 0 {
+		return
+	}
+	idx := t.Start.UnixNano() / int64(g.cfg.Window)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if idx > g.newest {
+		g.newest = idx
+		for k := range g.windows {
+			if k <= idx-int64(g.cfg.Windows) {
+				delete(g.windows, k)
+			}
+		}
+	}
+	if idx <= g.newest-int64(g.cfg.Windows) {
+		return // older than the retained history
+	}
+	w := g.windows[idx]
+	if w == nil {
+		w = &graphWindow{edges: map[edgeKey]*model.ServiceEdge{}, virtual: map[string]bool{}}
+		g.windows[idx] = w
+	}
+	for _, c := range calls {
+		k := edgeKey{c.Source, c.Target}
+		e := w.edges[k]
+		if e == nil {
+			e = &model.ServiceEdge{
+				Source: c.Source, Target: c.Target, ConnectionType: c.ConnectionType,
+				Latency: model.DataPoint{ExplicitBounds: g.cfg.LatencyBounds},
+			}
+			w.edges[k] = e
+		}
+		e.Calls++
+		if c.Error {
+			e.Errors++
+		}
+		histogram.Observe(&e.Latency, c.DurationMs)
+		if c.Virtual {
+			w.virtual[c.Target] = true
+		}
+	}
+}
+
+// Graph merges the windows overlapping [from, to) into one graph. A zero
+// from or to leaves that side open.
+func (g *GraphBuilder) Graph(from, to time.Time) *model.ServiceGraph {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	width := int64(g.cfg.Window)
+	out := &model.ServiceGraph{}
+	edges := map[edgeKey]*model.ServiceEdge{}
+	virtual := map[string]bool{}
+	for idx, w := range g.windows {
+		start := time.Unix(0, idx*width)
+		end := start.Add(g.cfg.Window)
+		if (!from.IsZero() && !end.After(from)) || (!to.IsZero() && !start.Before(to)) {
+			continue
+		}
+		if out.Start.IsZero() || start.Before(out.Start) {
+			out.Start = start
+		}
+		if end.After(out.End) {
+			out.End = end
+		}
+		for k, e := range w.edges {
+			m := edges[k]
+			if m == nil {
+				m = &model.ServiceEdge{
+					Source: e.Source, Target: e.Target, ConnectionType: e.ConnectionType,
+					Latency: model.DataPoint{ExplicitBounds: append([]float64(nil), e.Latency.ExplicitBounds...)},
+				}
+				edges[k] = m
+			}
+			m.Calls += e.Calls
+			m.Errors += e.Errors
+			mergeCounts(&m.Latency, &e.Latency)
+		}
+		for v := range w.virtual {
+			virtual[v] = true
+		}
+	}
+	return finishGraph(out, edges, virtual)
+}
+
+// Current returns the graph of the newest window.
+func (g *GraphBuilder) Current() *model.ServiceGraph {
+	g.mu.Lock()
+	start := time.Unix(0, g.newest*int64(g.cfg.Window))
+	g.mu.Unlock()
+	return g.Graph(start, start.Add(g.cfg.Window))
+}
+
+// mergeCounts adds src into dst; both use the builder's bounds.
+func mergeCounts(dst, src *model.DataPoint) {
+	if len(dst.BucketCounts) != len(src.BucketCounts) {
+		dst.BucketCounts = make([]uint64, len(src.BucketCounts))
+	}
+	for i, c := range src.BucketCounts {
+		dst.BucketCounts[i] += c
+	}
+	dst.Count += src.Count
+	dst.Sum += src.Sum
+}
+
+func finishGraph(g *model.ServiceGraph, edges map[edgeKey]*model.ServiceEdge, virtual map[string]bool) *model.ServiceGraph {
+	nodes := map[string]*model.ServiceNode{}
+	node := func(name string) *model.ServiceNode {
+		n := nodes[name]
+		if n == nil {
+			n = &model.ServiceNode{Name: name, Virtual: virtual[name]}
+			nodes[name] = n
+		}
+		return n
+	}
+	for _, e := range edges {
+		if e.Calls > 0 {
+			e.ErrorRate = float64(e.Errors) / float64(e.Calls)
+		}
+		if qs, err := histogram.Quantiles(&e.Latency, 0.5, 0.95, 0.99); err == nil {
+			e.LatencyP50Ms, e.LatencyP95Ms, e.LatencyP99Ms = qs[0].Value, qs[1].Value, qs[2].Value
+		}
+		node(e.Source)
+		target := node(e.Target)
+		target.Calls += e.Calls
+		target.Errors += e.Errors
+		g.Edges = append(g.Edges, *e)
+	}
+	for _, n := range nodes {
+		g.Nodes = append(g.Nodes, *n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Name < g.Nodes[j].Name })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Source != g.Edges[j].Source {
+			return g.Edges[i].Source < g.Edges[j].Source
+		}
+		return g.Edges[i].Target < g.Edges[j].Target
+	})
+	return g
+}
@@ -0,0 +1,271 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/traces/spanmetrics.go
+
+package traces
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/histogram"
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Span metric identity.
+const (
+	SpanMetricsNamespace    = "Traces"
+	SpanMetricsSubNamespace = "Spans"
+	SpanMetricsSource       = "spanmetrics"
+	MetricCalls             = "calls"
+	MetricDuration          = "duration"
+)
+
+// Attributes that key every span metric series. AttrSpanKind is also the
+// span attribute where otlp.SpanKindAttribute stores the kind.
+const (
+	AttrService  = "service.name"
+	AttrSpanName = "span.name"
+	AttrSpanKind = "span.kind"
+	AttrStatus   = "status.code"
+	AttrOverflow = "otel.metric.overflow"
+)
+
+// SpanMetricsConfig controls a SpanMetrics processor.
+type SpanMetricsConfig struct {
+	// Dimensions are extra span (then resource) attributes added to the
+	// series key, e.g. "http.method".
+	Dimensions []string `yaml:"dimensions,omitempty" json:"dimensions,omitempty"`
+
+	// MaxSeries caps the number of series. Spans that would create more
+	// are counted in one overflow series per service, marked with
+	// AttrOverflow. Default 1000.
+	MaxSeries int `yaml:"max_series,omitempty" json:"max_series,omitempty"`
+
+	// LatencyBounds are the duration histogram bounds in milliseconds.
+	// Default DefaultLatencyBounds.
+	LatencyBounds []float64 `yaml:"latency_bounds,omitempty" json:"latency_bounds,omitempty"`
+
+	// Delta resets the series after every Collect; otherwise they are
+	// cumulative.
+	Delta bool `yaml:"delta,omitempty" json:"delta,omitempty"`
+}
+
+// SpanMetrics turns spans into rate, error and duration metrics: a "calls"
+// counter and a "duration" histogram per service, span name, kind and
+// status. Each histogram bucket keeps the most recent span that fell in it
+// as an exemplar. It is safe for concurrent use.
+type SpanMetrics struct {
+	cfg SpanMetricsConfig
+
+	mu     sync.Mutex
+	series map[string]*spanSeries
+	start  time.Time // previous Collect, in delta mode
+}
+
+type spanSeries struct {
+	attrs     map[string]string
+	start     time.Time
+	duration  model.DataPoint
+	exemplars map[int]model.Exemplar // by bucket index
+	last      model.Exemplar
+}
+
+// NewSpanMetrics creates a SpanMetrics processor.
+func NewSpanMetrics(cfg SpanMetricsConfig) *SpanMetrics {
+	if cfg.MaxSeries <= 0 {
+		cfg.MaxSeries = 1000
+	}
+	if len(cfg.LatencyBounds) == 0 {
+		cfg.LatencyBounds = DefaultLatencyBounds
+	}
+	cfg.LatencyBounds = append([]float64(nil), cfg.LatencyBounds...)
+	return &SpanMetrics{cfg: cfg, series: map[string]*spanSeries{}}
+}
+
+// Add records one span.
+func (m *SpanMetrics) Add(s *model.TraceSpan) {
+	attrs := m.attributes(s)
+	key := seriesKey(attrs)
+	d := DurationMs(s)
+	now := End(s)
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ser := m.series[key]
+	if ser == nil {
+		if len(m.series) >= m.cfg.MaxSeries {
+			attrs = map[string]string{AttrService: attrs[AttrService], AttrOverflow: "true"}
+			key = seriesKey(attrs)
+			ser = m.series[key]
+		}
+		if ser == nil {
+			start := now
+			if m.cfg.Delta && !m.start.IsZero() {
+				start = m.start // delta series start at the previous Collect
+			}
+			ser = &spanSeries{
+				attrs:     attrs,
+				start:     start,
+				duration:  model.DataPoint{ExplicitBounds: m.cfg.LatencyBounds},
+				exemplars: map[int]model.Exemplar{},
+			}
+			m.series[key] = ser
+		}
+	}
+	histogram.Observe(&ser.duration, d)
+	ex := model.Exemplar{Value: d, Timestamp: now, TraceID: s.TraceID, SpanID: s.SpanID}
+	ser.exemplars[sort.SearchFloat64s(m.cfg.LatencyBounds, d)] = ex
+	ser.last = ex
+}
+
+// AddPayload records every span of a payload. Spans without Meta inherit
+// the payload's, so ServiceName can fall back to it.
+func (m *SpanMetrics) AddPayload(p *model.TracePayload) {
+	for i := range p.Traces {
+		s := p.Traces[i]
+		if s.Meta == nil {
+			s.Meta = p.Meta
+		}
+		m.Add(&s)
+	}
+}
+
+// AddTrace records every span of an assembled trace.
+func (m *SpanMetrics) AddTrace(t *Trace) {
+	t.Walk(func(n *Node) { m.Add(n.Span) })
+}
+
+// Collect returns the calls and duration metrics stamped with now, in
+// series key order.
+func (m *SpanMetrics) Collect(now time.Time) []model.Metric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	temporality := "cumulative"
+	if m.cfg.Delta {
+		temporality = "delta"
+	}
+	calls := model.Metric{
+		Namespace: SpanMetricsNamespace, SubNamespace: SpanMetricsSubNamespace,
+		Name: MetricCalls, Description: "Number of spans", Unit: "1",
+		Source: SpanMetricsSource, DataType: "sum", AggregationTemporality: temporality,
+	}
+	duration := model.Metric{
+		Namespace: SpanMetricsNamespace, SubNamespace: SpanMetricsSubNamespace,
+		Name: MetricDuration, Description: "Span duration", Unit: "ms",
+		Source: SpanMetricsSource, DataType: "histogram", AggregationTemporality: temporality,
+	}
+	for _, k := range keys {
+		ser := m.series[k]
+		calls.DataPoints = append(calls.DataPoints, model.DataPoint{
+			Attributes:     copyAttrs(ser.attrs),
+			StartTimestamp: ser.start,
+			Timestamp:      now,
+			Value:          float64(ser.duration.Count),
+			Exemplars:      []model.Exemplar{ser.last},
+		})
+
+		dp := ser.duration
+		dp.Attributes = copyAttrs(ser.attrs)
+		dp.StartTimestamp = ser.start
+		dp.Timestamp = now
+		dp.BucketCounts = append([]uint64(nil), ser.duration.BucketCounts...)
+		dp.ExplicitBounds = append([]float64(nil), ser.duration.ExplicitBounds...)
+		buckets := make([]int, 0, len(ser.exemplars))
+		for b := range ser.exemplars {
+			buckets = append(buckets, b)
+		}
+		sort.Ints(buckets)
+		for _, b := range buckets {
+			dp.Exemplars = append(dp.Exemplars, ser.exemplars[b])
+		}
+		duration.DataPoints = append(duration.DataPoints, dp)
+	}
+	if m.cfg.Delta {
+		m.series = map[string]*spanSeries{}
+		m.start = now
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return []model.Metric{calls, duration}
+}
+
+// attributes builds a span's series key attributes.
+func (m *SpanMetrics) attributes(s *model.TraceSpan) map[string]string {
+	status := strings.ToUpper(s.StatusCode)
+	if status == "" {
+		status = "UNSET"
+	}
+	attrs := map[string]string{
+		AttrService:  ServiceName(s),
+		AttrSpanName: s.Name,
+		AttrStatus:   status,
+	}
+	if kind := s.Attributes[AttrSpanKind]; kind != "" {
+		attrs[AttrSpanKind] = kind
+	}
+	for _, d := range m.cfg.Dimensions {
+		if v, ok := s.Attributes[d]; ok {
+			attrs[d] = v
+		} else if v, ok := s.ResourceAttrs[d]; ok {
+			attrs[d] = v
+		}
+	}
+	return attrs
+}
+
+func seriesKey(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(attrs[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func copyAttrs(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
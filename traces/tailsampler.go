@@ -0,0 +1,402 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/traces/tailsampler.go
+
+package traces
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Tail sampling policy types.
+const (
+	PolicyStatusCode    = "status_code"
+	PolicyLatency       = "latency"
+	PolicyProbabilistic = "probabilistic"
+	PolicyRateLimit     = "rate_limiting"
+	PolicyAttribute     = "attribute"
+	PolicyAnd           = "and"
+	PolicyComposite     = "composite"
+)
+
+// TailPolicy decides whether a complete trace is kept.
+type TailPolicy struct {
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type" json:"type"`
+
+	// status_code: keep if any span has one of these status codes.
+	// Default ["ERROR"].
+	StatusCodes []string `yaml:"status_codes,omitempty" json:"status_codes,omitempty"`
+
+	// latency: keep if the trace lasts at least this long.
+	ThresholdMs float64 `yaml:"threshold_ms,omitempty" json:"threshold_ms,omitempty"`
+
+	// probabilistic: keep this percentage of traces, chosen by a hash of
+	// the trace ID so every component sampling the same trace agrees.
+	Percentage float64 `yaml:"percentage,omitempty" json:"percentage,omitempty"`
+
+	// rate_limiting: keep at most this many traces per second for each
+	// root service, or only for Service when set.
+	TracesPerSecond int    `yaml:"traces_per_second,omitempty" json:"traces_per_second,omitempty"`
+	Service         string `yaml:"service,omitempty" json:"service,omitempty"`
+
+	// attribute: keep if any span (or its resource) has Key set to one of
+	// Values, or matching Regex. With neither, the key only has to exist.
+	Key    string   `yaml:"key,omitempty" json:"key,omitempty"`
+	Values []string `yaml:"values,omitempty" json:"values,omitempty"`
+	Regex  string   `yaml:"regex,omitempty" json:"regex,omitempty"`
+
+	// and: keep if every sub-policy keeps. composite: keep if any does,
+	// evaluated in order.
+	Policies []TailPolicy `yaml:"policies,omitempty" json:"policies,omitempty"`
+}
+
+// TailSamplerConfig controls a TailSampler.
+type TailSamplerConfig struct {
+	// DecisionWait is how long a trace may go without new spans before it
+	// is evaluated. Default 10s.
+	DecisionWait time.Duration `yaml:"decision_wait,omitempty" json:"decision_wait,omitempty"`
+
+	// MaxTraces and MaxSpansPerTrace bound the buffer; traces pushed out
+	// by them are evaluated early. Default 50000 traces.
+	MaxTraces        int `yaml:"max_traces,omitempty" json:"max_traces,omitempty"`
+	MaxSpansPerTrace int `yaml:"max_spans_per_trace,omitempty" json:"max_spans_per_trace,omitempty"`
+
+	// DecisionCache is how many past decisions are remembered so spans
+	// arriving after a decision follow it. Default MaxTraces.
+	DecisionCache int `yaml:"decision_cache,omitempty" json:"decision_cache,omitempty"`
+
+	// Policies are evaluated as a composite: a trace is kept if any keeps it.
+	Policies []TailPolicy `yaml:"policies" json:"policies"`
+}
+
+// Decision is the outcome for one trace.
+type Decision struct {
+	TraceID string
+	Sampled bool
+	Policy  string // name of the policy that kept the trace
+}
+
+// TailSampler buffers spans until their trace is complete and keeps or
+// drops whole traces according to its policies. It is safe for concurrent
+// use.
+type TailSampler struct {
+	cfg       TailSamplerConfig
+	assembler *Assembler
+	root      *policy
+	now       func() time.Time
+
+	mu        sync.Mutex
+	decisions map[string]bool
+	order     []string // decision cache, oldest first
+	sampled   uint64
+	dropped   uint64
+}
+
+type policy struct {
+	TailPolicy
+	re       *regexp.Regexp
+	values   map[string]bool
+	children []*policy
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTailSampler validates the policies and creates a TailSampler.
+func NewTailSampler(cfg TailSamplerConfig) (*TailSampler, error) {
+	if cfg.DecisionWait <= 0 {
+		cfg.DecisionWait = 10 * time.Second
+	}
+	if cfg.MaxTraces <= 0 {
+		cfg.MaxTraces = 50000
+	}
+	if cfg.DecisionCache <= 0 {
+		cfg.DecisionCache = cfg.MaxTraces
+	}
+	if len(cfg.Policies) == 0 {
+		return nil, fmt.Errorf("traces: tail sampler needs at least one policy")
+	}
+	root, err := compilePolicy(TailPolicy{Name: "root", Type: PolicyComposite, Policies: cfg.Policies})
+	if err != nil {
+		return nil, err
+	}
+	return &TailSampler{
+		cfg: cfg,
+		assembler: NewAssembler(AssemblerConfig{
+			Timeout:          cfg.DecisionWait,
+			MaxTraces:        cfg.MaxTraces,
+			MaxSpansPerTrace: cfg.MaxSpansPerTrace,
+		}),
+		root:      root,
+		now:       time.Now,
+		decisions: map[string]bool{},
+	}, nil
+}
+
+func compilePolicy(tp TailPolicy) (*policy, error) {
+	p := &policy{TailPolicy: tp}
+	switch tp.Type {
+	case PolicyStatusCode:
+		codes := tp.StatusCodes
+		if len(codes) == 0 {
+			codes = []string{"ERROR"}
+		}
+		p.values = map[string]bool{}
+		for _, c := range codes {
+			p.values[strings.ToUpper(c)] = true
+		}
+	case PolicyLatency:
+		if tp.ThresholdMs <= 0 {
+			return nil, fmt.Errorf("traces: policy %s: threshold_ms must be positive", tp.Name)
+		}
+	case PolicyProbabilistic:
+		if tp.Percentage < 0 || tp.Percentage > 100 {
+			return nil, fmt.Errorf("traces: policy %s: percentage must be between 0 and 100", tp.Name)
+		}
+	case PolicyRateLimit:
+		if tp.TracesPerSecond <= 0 {
+			return nil, fmt.Errorf("traces: policy %s: traces_per_second must be positive", tp.Name)
+		}
+		p.buckets = map[string]*rateBucket{}
+	case PolicyAttribute:
+		if tp.Key == "" {
+			return nil, fmt.Errorf("traces: policy %s: key is required", tp.Name)
+		}
+		if tp.Regex != "" {
+			re, err := regexp.Compile(tp.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("traces: policy %s: %w", tp.Name, err)
+			}
+			p.re = re
+		}
+		if len(tp.Values) > 0 {
+			p.values = map[string]bool{}
+			for _, v := range tp.Values {
+				p.values[v] = true
+			}
+		}
+	case PolicyAnd, PolicyComposite:
+		if len(tp.Policies) == 0 {
+			return nil, fmt.Errorf("traces: policy %s: %s needs sub-policies", tp.Name, tp.Type)
+		}
+		for _, sub := range tp.Policies {
+			c, err := compilePolicy(sub)
+			if err != nil {
+				return nil, err
+			}
+			p.children = append(p.children, c)
+		}
+	default:
+		return nil, fmt.Errorf("traces: policy %s: unknown type %q", tp.Name, tp.Type)
+	}
+	return p, nil
+}
+
+// Add buffers a span. It returns spans that are ready to be forwarded:
+// the span itself if its trace was already kept, and the spans of traces
+// decided early because the buffer was full.
+func (s *TailSampler) Add(span model.TraceSpan) []model.TraceSpan {
+	s.mu.Lock()
+	sampled, decided := s.decisions[span.TraceID]
+	s.mu.Unlock()
+	if decided {
+		if sampled {
+			return []model.TraceSpan{span}
+		}
+		return nil
+	}
+	return s.decide(s.assembler.Add(span))
+}
+
+// AddPayload buffers every span of a payload. Spans without Meta inherit
+// the payload's.
+func (s *TailSampler) AddPayload(p *model.TracePayload) []model.TraceSpan {
+	var out []model.TraceSpan
+	for _, span := range p.Traces {
+		if span.Meta == nil {
+			span.Meta = p.Meta
+		}
+		out = append(out, s.Add(span)...)
+	}
+	return out
+}
+
+// Flush evaluates traces idle for DecisionWait and returns the spans kept.
+func (s *TailSampler) Flush() []model.TraceSpan {
+	return s.decide(s.assembler.Flush())
+}
+
+// FlushAll evaluates every buffered trace, e.g. on shutdown.
+func (s *TailSampler) FlushAll() []model.TraceSpan {
+	return s.decide(s.assembler.FlushAll())
+}
+
+// Pending returns the number of traces awaiting a decision.
+func (s *TailSampler) Pending() int {
+	return s.assembler.Pending()
+}
+
+// Stats returns the number of traces kept and dropped so far.
+func (s *TailSampler) Stats() (sampled, dropped uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sampled, s.dropped
+}
+
+// Evaluate applies the policies to an assembled trace.
+func (s *TailSampler) Evaluate(t *Trace) Decision {
+	d := Decision{TraceID: t.TraceID}
+	for _, p := range s.root.children {
+		if p.evaluate(t, s.now()) {
+			d.Sampled, d.Policy = true, p.Name
+			break
+		}
+	}
+	return d
+}
+
+func (s *TailSampler) decide(ts []*Trace) []model.TraceSpan {
+	var out []model.TraceSpan
+	for _, t := range ts {
+		d := s.Evaluate(t)
+		s.remember(d)
+		if !d.Sampled {
+			continue
+		}
+		t.Walk(func(n *Node) { out = append(out, *n.Span) })
+	}
+	return out
+}
+
+func (s *TailSampler) remember(d Decision) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d.Sampled {
+		s.sampled++
+	} else {
+		s.dropped++
+	}
+	if _, ok := s.decisions[d.TraceID]; !ok {
+		s.order = append(s.order, d.TraceID)
+	}
+	s.decisions[d.TraceID] = d.Sampled
+	for len(s.order) > s.cfg.DecisionCache {
+		delete(s.decisions, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (p *policy) evaluate(t *Trace, now time.Time) bool {
+	switch p.Type {
+	case PolicyStatusCode:
+		return anySpan(t, func(s *model.TraceSpan) bool { return p.values[strings.ToUpper(s.StatusCode)] })
+	case PolicyLatency:
+		return t.DurationMs() >= p.ThresholdMs
+	case PolicyProbabilistic:
+		h := fnv.New64a()
+		h.Write([]byte(t.TraceID))
+		return float64(h.Sum64()%10000) < p.Percentage*100
+	case PolicyRateLimit:
+		svc := ""
+		if root := rootSpan(t); root != nil {
+			svc = ServiceName(root.Span)
+		}
+		if p.Service != "" && p.Service != svc {
+			return false
+		}
+		return p.allow(svc, now)
+	case PolicyAttribute:
+		return anySpan(t, func(s *model.TraceSpan) bool {
+			v, ok := s.Attributes[p.Key]
+			if !ok {
+				v, ok = s.ResourceAttrs[p.Key]
+			}
+			switch {
+			case !ok:
+				return false
+			case p.values != nil && p.values[v]:
+				return true
+			case p.re != nil && p.re.MatchString(v):
+				return true
+			}
+			return p.values == nil && p.re == nil
+		})
+	case PolicyAnd:
+		for _, c := range p.children {
+			if !c.evaluate(t, now) {
+				return false
+			}
+		}
+		return true
+	case PolicyComposite:
+		for _, c := range p.children {
+			if c.evaluate(t, now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allow takes a token from the service's bucket.
+func (p *policy) allow(svc string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rate := float64(p.TracesPerSecond)
+	b := p.buckets[svc]
+	if b == nil {
+		b = &rateBucket{tokens: rate, last: now}
+		p.buckets[svc] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > rate {
+		b.tokens = rate
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func anySpan(t *Trace, fn func(*model.TraceSpan) bool) bool {
+	for _, n := range t.Spans {
+		if fn(n.Span) {
+			return true
+		}
+	}
+	return false
+}
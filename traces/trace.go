@@ -0,0 +1,338 @@
+/*
+SPDX-License-Identifier: GPL-3.0-or-later
+
+Copyright (C) 2025 Aaron Mathis aaron.mathis@gmail.com
+
+This file is part of GoSight.
+
+GoSight is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+GoSight is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with GoSight. If not, see https://www.gnu.org/licenses/.
+*/
+
+// shared/traces/trace.go
+
+// Package traces works with assembled traces: it buffers model.TraceSpan
+// values by trace ID until a trace is complete, builds the span tree, and
+// analyses it (orphans, self time, critical path and per-service latency).
+package traces
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aaronlmathis/gosight-shared/model"
+)
+
+// Node is a span in an assembled trace tree.
+type Node struct {
+	Span     *model.TraceSpan
+	Parent   *Node
+	Children []*Node // ordered by start time
+
+	// SelfTimeMs is the part of the span's duration not covered by any
+	// child span.
+	SelfTimeMs float64
+
+	// Critical is set when the span contributes to the critical path.
+	Critical bool
+}
+
+// Trace is an assembled trace.
+type Trace struct {
+	TraceID string
+
+	// Root is the span without a parent. When several spans have no parent
+	// the earliest is Root and the others are listed in Orphans.
+	Root *Node
+
+	// Orphans are subtrees whose parent span never arrived, ordered by start
+	// time. If there is no Root, the whole trace is made of orphans.
+	Orphans []*Node
+
+	// Spans indexes every node by span ID.
+	Spans map[string]*Node
+
+	Start time.Time
+	End   time.Time
+}
+
+// Segment is a stretch of the critical path spent in one span.
+type Segment struct {
+	Span  *model.TraceSpan
+	Start time.Time
+	End   time.Time
+}
+
+// DurationMs returns the segment length in milliseconds.
+func (s Segment) DurationMs() float64 {
+	return float64(s.End.Sub(s.Start)) / float64(time.Millisecond)
+}
+
+// ServiceLatency is a service's share of a trace.
+type ServiceLatency struct {
+	Service        string  `json:"service"`
+	Spans          int     `json:"spans"`
+	Errors         int     `json:"errors"`
+	SelfTimeMs     float64 `json:"self_time_ms"`
+	CriticalPathMs float64 `json:"critical_path_ms"`
+}
+
+// ServiceName returns the service a span belongs to: ServiceName, then the
+// service.name resource attribute, then Meta.
+func ServiceName(s *model.TraceSpan) string {
+	if s.ServiceName != "" {
+		return s.ServiceName
+	}
+	if v := s.ResourceAttrs["service.name"]; v != "" {
+		return v
+	}
+	if s.Meta != nil {
+		if s.Meta.ServiceName != "" {
+			return s.Meta.ServiceName
+		}
+		return s.Meta.Service
+	}
+	return ""
+}
+
+// End returns a span's end time, derived from DurationMs if EndTime is unset.
+func End(s *model.TraceSpan) time.Time {
+	if !s.EndTime.IsZero() {
+		return s.EndTime
+	}
+	return s.StartTime.Add(time.Duration(s.DurationMs * float64(time.Millisecond)))
+}
+
+// DurationMs returns a span's duration, derived from its timestamps if
+// DurationMs is unset.
+func DurationMs(s *model.TraceSpan) float64 {
+	if s.DurationMs > 0 {
+		return s.DurationMs
+	}
+	return float64(End(s).Sub(s.StartTime)) / float64(time.Millisecond)
+}
+
+// IsError reports whether a span's status is ERROR.
+func IsError(s *model.TraceSpan) bool {
+	return s.StatusCode == "ERROR" || s.StatusCode == "error" || s.StatusCode == "STATUS_CODE_ERROR"
+}
+
+// Build assembles spans into a tree. Spans are expected to share one trace
+// ID; duplicates of a span ID keep the first copy.
+func Build(spans []model.TraceSpan) *Trace {
+	t := &Trace{Spans: make(map[string]*Node, len(spans))}
+	nodes := make([]*Node, 0, len(spans))
+	for i := range spans {
+		s := &spans[i]
+		if _, dup := t.Spans[s.SpanID]; dup {
+			continue
+		}
+		n := &Node{Span: s}
+		t.Spans[s.SpanID] = n
+		nodes = append(nodes, n)
+		if t.TraceID == "" {
+			t.TraceID = s.TraceID
+		}
+		if t.Start.IsZero() || s.StartTime.Before(t.Start) {
+			t.Start = s.StartTime
+		}
+		if end := End(s); end.After(t.End) {
+			t.End = end
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Span.StartTime.Before(nodes[j].Span.StartTime)
+	})
+
+	for _, n := range nodes {
+		parentID := n.Span.ParentSpanID
+		if parent, ok := t.Spans[parentID]; ok && parentID != "" && parent != n {
+			n.Parent = parent
+			parent.Children = append(parent.Children, n)
+			continue
+		}
+		if parentID == "" && t.Root == nil {
+			t.Root = n
+			continue
+		}
+		t.Orphans = append(t.Orphans, n)
+	}
+	for _, n := range nodes {
+		n.SelfTimeMs = selfTime(n)
+	}
+	return t
+}
+
+// DurationMs returns the time between the first span start and the last
+// span end.
+func (t *Trace) DurationMs() float64 {
+	return float64(t.End.Sub(t.Start)) / float64(time.Millisecond)
+}
+
+// Complete reports whether the trace has a root and no orphans.
+func (t *Trace) Complete() bool {
+	return t.Root != nil && len(t.Orphans) == 0
+}
+
+// Walk visits every node depth-first, the root first and then orphans.
+func (t *Trace) Walk(fn func(*Node)) {
+	var visit func(*Node)
+	visit = func(n *Node) {
+		fn(n)
+		for _, c := range n.Children {
+			visit(c)
+		}
+	}
+	for _, r := range t.roots() {
+		visit(r)
+	}
+}
+
+func (t *Trace) roots() []*Node {
+	if t.Root == nil {
+		return t.Orphans
+	}
+	return append([]*Node{t.Root}, t.Orphans...)
+}
+
+// rootSpan returns the root, or the earliest orphan when there is none.
+func rootSpan(t *Trace) *Node {
+	if t.Root != nil {
+		return t.Root
+	}
+	if len(t.Orphans) > 0 {
+		return t.Orphans[0]
+	}
+	return nil
+}
+
+// selfTime is the span's duration minus the union of its children's
+// intervals, clipped to the span.
+func selfTime(n *Node) float64 {
+	start, end := n.Span.StartTime, End(n.Span)
+	total := end.Sub(start)
+	if total <= 0 {
+		return 0
+	}
+	covered := time.Duration(0)
+	cursor := start
+	for _, c := range n.Children { // already ordered by start
+		cs, ce := c.Span.StartTime, End(c.Span)
+		if cs.Before(cursor) {
+			cs = cursor
+		}
+		if ce.After(end) {
+			ce = end
+		}
+		if ce.After(cs) {
+			covered += ce.Sub(cs)
+			cursor = ce
+		}
+	}
+	return float64(total-covered) / float64(time.Millisecond)
+}
+
+// CriticalPath returns the chain of work that determined the trace's
+// duration, in chronological order, and marks the nodes involved. Starting
+// at the root's end, it repeatedly follows the child that finished last
+// before the current point in time; time not covered by a child is
+// attributed to the parent. Without a root the earliest orphan is used.
+func (t *Trace) CriticalPath() []Segment {
+	root := rootSpan(t)
+	if root == nil {
+		return nil
+	}
+	var segs []Segment
+	criticalPath(root, End(root.Span), &segs)
+	for i, j := 0, len(segs)-1; i < j; i, j = i+1, j-1 {
+		segs[i], segs[j] = segs[j], segs[i]
+	}
+	return segs
+}
+
+// criticalPath appends segments for n ending no later than until, latest
+// first.
+func criticalPath(n *Node, until time.Time, segs *[]Segment) {
+	n.Critical = true
+	start := n.Span.StartTime
+	cursor := End(n.Span)
+	if until.Before(cursor) {
+		cursor = until
+	}
+
+	children := append([]*Node(nil), n.Children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		return End(children[i].Span).After(End(children[j].Span))
+	})
+	for _, c := range children {
+		if !cursor.After(start) {
+			break
+		}
+		cs, ce := c.Span.StartTime, End(c.Span)
+		if !cs.Before(cursor) || !ce.After(start) {
+			continue // starts after the point we are tracing back from
+		}
+		if ce.After(cursor) {
+			ce = cursor
+		}
+		if ce.Before(cursor) {
+			*segs = append(*segs, Segment{Span: n.Span, Start: ce, End: cursor})
+		}
+		criticalPath(c, ce, segs)
+		cursor = cs
+		if cursor.Before(start) {
+			cursor = start
+		}
+	}
+	if cursor.After(start) {
+		*segs = append(*segs, Segment{Span: n.Span, Start: start, End: cursor})
+	}
+}
+
+// ServiceBreakdown sums span counts, errors, self time and critical path
+// time per service, largest self time first.
+func (t *Trace) ServiceBreakdown() []ServiceLatency {
+	by := map[string]*ServiceLatency{}
+	get := func(s *model.TraceSpan) *ServiceLatency {
+		name := ServiceName(s)
+		sl := by[name]
+		if sl == nil {
+			sl = &ServiceLatency{Service: name}
+			by[name] = sl
+		}
+		return sl
+	}
+	t.Walk(func(n *Node) {
+		sl := get(n.Span)
+		sl.Spans++
+		sl.SelfTimeMs += n.SelfTimeMs
+		if IsError(n.Span) {
+			sl.Errors++
+		}
+	})
+	for _, seg := range t.CriticalPath() {
+		get(seg.Span).CriticalPathMs += seg.DurationMs()
+	}
+
+	out := make([]ServiceLatency, 0, len(by))
+	for _, sl := range by {
+		out = append(out, *sl)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SelfTimeMs != out[j].SelfTimeMs {
+			return out[i].SelfTimeMs > out[j].SelfTimeMs
+		}
+		return out[i].Service < out[j].Service
+	})
+	return out
+}